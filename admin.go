@@ -0,0 +1,226 @@
+package foundation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/http"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/easonchen147/foundation/buildinfo"
+	"github.com/easonchen147/foundation/cfg"
+	"github.com/easonchen147/foundation/container"
+	"github.com/easonchen147/foundation/health"
+	"github.com/easonchen147/foundation/log"
+	"github.com/easonchen147/foundation/metrics"
+	"github.com/easonchen147/foundation/middleware"
+
+	"github.com/gin-contrib/pprof"
+	"github.com/gin-gonic/gin"
+)
+
+// 初始化管理端口路由
+func initAdminEngine(cfg *cfg.AppConfig, components []Component, registerAdminRoutes func(*gin.RouterGroup)) *gin.Engine {
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	registerOpsRoutes(cfg, components, &engine.RouterGroup, registerAdminRoutes, true)
+	return engine
+}
+
+// 注册运维接口：健康检查、pprof、prometheus指标、运行时调试接口以及应用自定义的运维路由。
+// standalone为false时注册在业务端口上，此时pprof只在开发环境注册，prometheus指标与构建信息只在开发环境或配置了admin的账号、ip白名单时注册，
+// 运行时调试接口只在配置了admin的账号或ip白名单时注册
+func registerOpsRoutes(config *cfg.AppConfig, components []Component, router *gin.RouterGroup,
+	registerAdminRoutes func(*gin.RouterGroup), standalone bool) {
+	// 健康检查，供kubelet等探测使用，不做鉴权
+	health.RegisterRoutes(router)
+
+	if config.AdminConfig != nil {
+		router = router.Group("", middleware.AdminAuth(config.AdminConfig.User, config.AdminConfig.Pass, config.AdminConfig.AllowIps))
+	}
+
+	// 性能监控，未启用独立管理端口时只在开发环境注册
+	// to look at the heap profile: go tool ip:port/dev/pprof/heap
+	// to look at a 30-second CPU profile: go tool ip:port/dev/pprof/profile
+	// to look at the goroutine blocking profile: go tool ip:port/dev/pprof/block
+	// to collect a 5-second execution trace: wget ip:port/debug/pprof/trace?seconds=5
+	if standalone || config.IsDevEnv() {
+		pprof.RouteRegister(router, "dev/pprof")
+	}
+
+	// prometheus指标与构建信息，业务端口上只在配置了访问控制或开发环境时注册
+	if standalone || adminProtected(config) || config.IsDevEnv() {
+		router.GET("/metrics", gin.WrapH(metrics.Handler()))
+		router.GET("/version", func(c *gin.Context) {
+			c.JSON(http.StatusOK, buildinfo.Get())
+		})
+	}
+
+	// 运行时调试，可以修改日志级别、重新加载配置，不能在未鉴权的业务端口上开放
+	if standalone || adminProtected(config) {
+		registerDebugRoutes(router, components)
+	} else {
+		log.Warn(context.Background(), "Debug routes are disabled on the business port, set admin_port or admin.user/pass/allow_ips to enable them")
+	}
+
+	if registerAdminRoutes != nil {
+		registerAdminRoutes(router)
+	}
+}
+
+// 是否配置了管理接口的访问控制
+func adminProtected(config *cfg.AppConfig) bool {
+	conf := config.AdminConfig
+	return conf != nil && ((conf.User != "" && conf.Pass != "") || len(conf.AllowIps) > 0)
+}
+
+func registerDebugRoutes(router *gin.RouterGroup, components []Component) {
+	debug := router.Group("/debug")
+	debug.GET("/log/level", func(c *gin.Context) {
+		c.JSON(http.StatusOK, log.Levels())
+	})
+	debug.PUT("/log/level", setLogLevel)
+	debug.GET("/config", func(c *gin.Context) {
+		c.JSON(http.StatusOK, cfg.Current().Redacted())
+	})
+	debug.POST("/config/reload", reloadConfig)
+	debug.GET("/components", func(c *gin.Context) {
+		c.JSON(http.StatusOK, componentStatus(c, components))
+	})
+	debug.GET("/goroutines", goroutines)
+}
+
+type setLogLevelReq struct {
+	Name     string `json:"name"` // app、access或sql，为空时调整全部日志
+	Level    string `json:"level" binding:"required"`
+	Duration string `json:"duration"` // 临时调整的时长，如"10m"，到期后恢复为配置的级别，为空时永久调整
+}
+
+// 调整日志级别，如 curl -X PUT ip:port/debug/log/level -d '{"level":"debug","duration":"10m"}'
+func setLogLevel(c *gin.Context) {
+	var req setLogLevelReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var revertAfter time.Duration
+	if req.Duration != "" {
+		var err error
+		if revertAfter, err = time.ParseDuration(req.Duration); err != nil || revertAfter <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid duration " + req.Duration})
+			return
+		}
+	}
+	if err := log.SetLevel(req.Name, req.Level, revertAfter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log.Warn(c, "Log level of %q changed to %s by %s, revert after: %s", req.Name, req.Level, c.ClientIP(), revertAfter)
+	c.JSON(http.StatusOK, log.Levels())
+}
+
+// 重新加载配置文件，校验失败时保持当前配置不变
+func reloadConfig(c *gin.Context) {
+	config, err := cfg.Reload()
+	if err != nil {
+		log.Error(c, "Reload config failed, keep the current config, error: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log.Warn(c, "Config reloaded from %s by %s", strings.Join(config.Layers(), ","), c.ClientIP())
+	c.JSON(http.StatusOK, config.Redacted())
+}
+
+type componentReport struct {
+	Components []Component        `json:"components"` // 启用的基础组件
+	Instances  []container.Entry  `json:"instances"`  // 容器中注册的组件实例，resolved表示是否已建立连接
+	Health     *health.Report     `json:"health"`
+	Uptime     string             `json:"uptime"`
+	Build      buildinfo.Info     `json:"build"`
+	Runtime    map[string]float64 `json:"runtime"`
+}
+
+var startTime = time.Now()
+
+func componentStatus(c *gin.Context, components []Component) *componentReport {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return &componentReport{
+		Components: components,
+		Instances:  container.Default.Entries(),
+		Health:     health.Check(c),
+		Uptime:     time.Since(startTime).Round(time.Second).String(),
+		Build:      buildinfo.Get(),
+		Runtime: map[string]float64{
+			"goroutines":  float64(runtime.NumGoroutine()),
+			"heap_alloc":  float64(mem.HeapAlloc),
+			"heap_inuse":  float64(mem.HeapInuse),
+			"num_gc":      float64(mem.NumGC),
+			"gc_pause_ns": float64(mem.PauseTotalNs),
+		},
+	}
+}
+
+// goroutineGroup 堆栈相同的goroutine
+type goroutineGroup struct {
+	Count int      `json:"count"`
+	Stack []string `json:"stack"`
+}
+
+// 按堆栈分组统计goroutine数量，按数量降序排列，可通过filter参数只保留堆栈中包含该字符串的分组
+func goroutines(c *gin.Context) {
+	var buf bytes.Buffer
+	if err := runtimepprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	groups := parseGoroutineProfile(&buf)
+
+	filter := c.Query("filter")
+	total := 0
+	result := make([]goroutineGroup, 0, len(groups))
+	for _, group := range groups {
+		total += group.Count
+		if filter == "" || strings.Contains(strings.Join(group.Stack, "\n"), filter) {
+			result = append(result, group)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Count > result[j].Count
+	})
+	c.JSON(http.StatusOK, gin.H{"total": total, "groups": result})
+}
+
+// 解析debug=1格式的goroutine profile：每个分组以"数量 @ 地址"开头，随后每行为"#	地址	函数+偏移	文件:行号"
+func parseGoroutineProfile(buf *bytes.Buffer) []goroutineGroup {
+	var groups []goroutineGroup
+	var current *goroutineGroup
+	scanner := bufio.NewScanner(buf)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.Contains(line, " @ "):
+			count, err := strconv.Atoi(strings.TrimSpace(line[:strings.Index(line, " @ ")]))
+			if err != nil {
+				current = nil
+				continue
+			}
+			groups = append(groups, goroutineGroup{Count: count})
+			current = &groups[len(groups)-1]
+		case strings.HasPrefix(line, "#") && current != nil:
+			fields := strings.Fields(strings.TrimPrefix(line, "#"))
+			if len(fields) >= 3 {
+				current.Stack = append(current.Stack, fields[1]+" "+fields[len(fields)-1])
+			}
+		case line == "":
+			current = nil
+		}
+	}
+	return groups
+}
@@ -0,0 +1,119 @@
+package foundation_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/easonchen147/foundation"
+	"github.com/easonchen147/foundation/foundationtest"
+
+	"github.com/gin-gonic/gin"
+)
+
+func status(t *testing.T, method, url string) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(`{"level":"info"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// 未配置独立管理端口及访问控制时，运行时调试接口不能注册在业务端口上
+func TestDebugRoutesOnBusinessPort(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		want     int
+	}{
+		{"no admin", nil, http.StatusNotFound},
+		{"basic auth", map[string]interface{}{"admin.user": "ops", "admin.pass": "pw"}, http.StatusUnauthorized},
+		{"allow ips", map[string]interface{}{"admin.allow_ips": []string{"127.0.0.1"}}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := foundationtest.New(t, func(*gin.Engine) {}, foundationtest.WithSettings(tt.settings))
+			for _, route := range []struct{ method, path string }{
+				{http.MethodGet, "/debug/config"},
+				{http.MethodPut, "/debug/log/level"},
+				{http.MethodGet, "/debug/goroutines"},
+			} {
+				if got := status(t, route.method, env.URL(route.path)); got != tt.want {
+					t.Errorf("%s %s = %d, want %d", route.method, route.path, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// 未配置独立管理端口时，pprof只在开发环境注册在业务端口上
+func TestPprofOnBusinessPort(t *testing.T) {
+	tests := []struct {
+		env  string
+		want int
+	}{
+		{"dev", http.StatusOK},
+		{"test", http.StatusNotFound},
+		{"prod", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			env := foundationtest.New(t, func(*gin.Engine) {}, foundationtest.WithSettings(map[string]interface{}{"env": tt.env}))
+			if got := status(t, http.MethodGet, env.URL("/dev/pprof/cmdline")); got != tt.want {
+				t.Errorf("GET /dev/pprof/cmdline = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// 配置了管理接口的访问控制时，健康检查仍然不需要鉴权
+func TestHealthRoutesSkipAdminAuth(t *testing.T) {
+	env := foundationtest.New(t, func(*gin.Engine) {}, foundationtest.WithSettings(map[string]interface{}{
+		"admin.user": "ops",
+		"admin.pass": "pw",
+	}))
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if got := status(t, http.MethodGet, env.URL(path)); got != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", path, got)
+		}
+	}
+}
+
+// 业务端口上的运维路由发生panic时由recovery处理
+func TestOpsRoutesRecoverPanic(t *testing.T) {
+	env := foundationtest.New(t, func(*gin.Engine) {}, foundationtest.WithAppOptions(
+		foundation.WithAdminRoutes(func(router *gin.RouterGroup) {
+			router.GET("/boom", func(*gin.Context) { panic("boom") })
+		}),
+	))
+	if got := status(t, http.MethodGet, env.URL("/boom")); got != http.StatusInternalServerError {
+		t.Errorf("GET /boom = %d, want 500", got)
+	}
+}
+
+// 未配置访问控制时，业务端口上不开放prometheus指标与构建信息
+func TestMetricsHiddenOnBusinessPort(t *testing.T) {
+	env := foundationtest.New(t, func(*gin.Engine) {})
+	for _, path := range []string{"/metrics", "/version"} {
+		if got := status(t, http.MethodGet, env.URL(path)); got != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", path, got)
+		}
+	}
+}
+
+func TestMetricsBehindAdminAccessControl(t *testing.T) {
+	env := foundationtest.New(t, func(*gin.Engine) {}, foundationtest.WithSettings(map[string]interface{}{
+		"admin.allow_ips": []string{"127.0.0.1"},
+	}))
+	for _, path := range []string{"/metrics", "/version"} {
+		if got := status(t, http.MethodGet, env.URL(path)); got != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", path, got)
+		}
+	}
+}
@@ -2,121 +2,508 @@ package foundation
 
 import (
 	"context"
+	"crypto/tls"
+	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/easonchen147/foundation/buildinfo"
 	"github.com/easonchen147/foundation/cache"
 	"github.com/easonchen147/foundation/cfg"
+	"github.com/easonchen147/foundation/container"
+	"github.com/easonchen147/foundation/db"
+	"github.com/easonchen147/foundation/health"
 	"github.com/easonchen147/foundation/kafka"
 	"github.com/easonchen147/foundation/log"
+	"github.com/easonchen147/foundation/metrics"
 	"github.com/easonchen147/foundation/middleware"
+	"github.com/easonchen147/foundation/mongo"
+	"github.com/easonchen147/foundation/response"
+	"github.com/easonchen147/foundation/rpc"
+	"github.com/easonchen147/foundation/tlscert"
+	"github.com/easonchen147/foundation/upgrade"
+	"github.com/easonchen147/foundation/util"
+	"github.com/easonchen147/foundation/worker"
 
-	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
 )
 
 // StartServer 应用入口点
 func StartServer(registerRoutes func(*gin.Engine)) {
-	// 启动Web服务
-	err := startServer(cfg.AppConf, registerRoutes)
+	app, err := New(WithRoutes(registerRoutes), WithComponents(AllComponents...))
 	if err != nil {
 		panic(fmt.Sprintf("Server started failed: %s", err))
 	}
+	// 启动Web服务
+	if err = app.Run(context.Background()); err != nil {
+		panic(fmt.Sprintf("Server started failed: %s", err))
+	}
+}
+
+// App 应用对象，负责加载配置、初始化组件以及管理服务生命周期
+type App struct {
+	opts   *options
+	config *cfg.AppConfig
+	engine *gin.Engine
+	server *http.Server
+
+	adminServer *http.Server // 未启用管理端口时为nil
+
+	startHooks []Hook
+	stopHooks  []Hook
+	serveErr   chan error
+
+	// 应用运行期间有效的上下文，停止完成后取消
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	certReloader *tlscert.Reloader // 未启用https时为nil
+
+	workers *worker.Manager
+
+	grpcServer *grpc.Server // 未注册grpc服务时为nil
+	grpcMux    *rpc.Mux     // grpc使用独立端口时为nil
+
+	modules []Module // 按初始化顺序排列
+
+	serving  atomic.Bool // http服务是否已启动
+	stopOnce sync.Once
+	stopErr  error
 }
 
-// startServer 启动服务
-func startServer(cfg *cfg.AppConfig, registerRoutes func(*gin.Engine)) error {
-	server := &http.Server{
-		Addr:    cfg.HttpAddr + ":" + strconv.Itoa(cfg.HttpPort),
-		Handler: initEngine(cfg, registerRoutes),
+// New 创建应用，加载配置并初始化指定的组件
+func New(opts ...Option) (*App, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
 	}
-	ctx, cancel := context.WithCancel(context.Background())
-	go listenToSystemSignals(cancel)
+	return newApp(o)
+}
 
-	go func() {
-		<-ctx.Done()
-		shutdown(server)
-	}()
+func newApp(o *options) (*App, error) {
+	config, err := loadConfig(o)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	modules, err := sortModules(o.modules)
+	if err != nil {
+		return nil, err
+	}
+	o.components = mergeComponents(o.components, modules)
+	// 健康检查项与停止标记为全局状态，清除之前创建的应用留下的状态
+	health.Reset()
 
-	log.Info(context.Background(), "Server started success")
-	err := server.ListenAndServe()
-	if errors.Is(err, http.ErrServerClosed) {
-		log.Info(context.Background(), "Server was shutdown gracefully")
-		return nil
+	log.InitLog(config)
+	if unknown := config.UnknownEnv(); len(unknown) > 0 {
+		log.Warn(context.Background(), "Ignore env %s with prefix %s_, they match no config key", strings.Join(unknown, ","), cfg.EnvPrefix())
 	}
+	util.InitHttpClient(config)
+	if o.envelope != nil {
+		response.SetEnvelope(o.envelope)
+	}
+	middleware.InitSign(config)
+	middleware.InitTs(config)
 
-	return err
+	for _, component := range o.components {
+		if err := initComponent(config, component); err != nil {
+			return nil, fmt.Errorf("init %s failed: %s", component, err)
+		}
+		health.Register(componentCheckers(component)...)
+		for _, collector := range componentCollectors(component) {
+			if err := metrics.Register(collector); err != nil {
+				return nil, fmt.Errorf("register %s metrics failed: %s", component, err)
+			}
+		}
+	}
+	if err := metrics.Register(metrics.NewGoPoolCollector(util.GoPoolStats)); err != nil {
+		return nil, fmt.Errorf("register go pool metrics failed: %s", err)
+	}
+
+	registerRoutes := o.registerRoutes
+	if registerRoutes == nil {
+		registerRoutes = func(*gin.Engine) {}
+	}
+	engine := initEngine(config, o.components, registerRoutes, o.registerAdminRoutes, o.recoveryHandler)
+
+	app := &App{
+		opts:     o,
+		config:   config,
+		engine:   engine,
+		server:   newHttpServer(config, config.HttpAddr+":"+strconv.Itoa(config.HttpPort), engine),
+		serveErr: make(chan error, 1),
+		workers:  worker.NewManager(),
+	}
+	app.ctx, app.cancel = context.WithCancel(context.Background())
+	if config.TlsEnabled() {
+		reloader, err := tlscert.NewReloader(config.TlsCertFile, config.TlsKeyFile, config.TlsClientCaFile)
+		if err != nil {
+			return nil, err
+		}
+		app.certReloader = reloader
+		app.server.TLSConfig = reloader.TLSConfig()
+	}
+	if o.registerGrpc != nil {
+		app.grpcServer = rpc.NewServer(o.grpcOptions...)
+		o.registerGrpc(app.grpcServer)
+		if !config.GrpcStandalone() {
+			app.grpcMux = rpc.NewMux(app.grpcServer, engine)
+			app.server.Handler = app.grpcMux.Handler(config.TlsEnabled())
+		}
+	}
+	if config.AdminEnabled() {
+		app.adminServer = newHttpServer(config, config.AdminAddr+":"+strconv.Itoa(config.AdminPort),
+			initAdminEngine(config, o.components, o.registerAdminRoutes))
+	}
+	if config.ConfigWatch && config.File != "" {
+		if err := app.RegisterWorker("config-watch", watchConfig, worker.WithRestart(time.Second, 30*time.Second)); err != nil {
+			return nil, err
+		}
+	}
+	if err := app.initModules(modules); err != nil {
+		return nil, err
+	}
+	app.registerBuiltinHooks()
+	return app, nil
 }
 
-// 初始化gin路由
-func initEngine(cfg *cfg.AppConfig, registerRoutes func(*gin.Engine)) *gin.Engine {
-	gin.SetMode(func() string {
-		if cfg.IsDevEnv() {
-			return gin.DebugMode
+// 创建http服务，超时时间以秒为单位，为0时不限制
+func newHttpServer(config *cfg.AppConfig, addr string, handler http.Handler) *http.Server {
+	conf := config.ServerConfig
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: time.Second * time.Duration(conf.ReadHeaderTimeout),
+		ReadTimeout:       time.Second * time.Duration(conf.ReadTimeout),
+		WriteTimeout:      time.Second * time.Duration(conf.WriteTimeout),
+		IdleTimeout:       time.Second * time.Duration(conf.IdleTimeout),
+		MaxHeaderBytes:    conf.MaxHeaderBytes,
+	}
+}
+
+// 加载配置并设置为全局配置
+func loadConfig(o *options) (*cfg.AppConfig, error) {
+	config := o.config
+	if config == nil {
+		configFile := o.configFile
+		if configFile == "" {
+			configFile = cfg.DefaultConfigFile()
 		}
-		return gin.ReleaseMode
-	}())
+		if o.envPrefix != nil {
+			cfg.SetEnvPrefix(*o.envPrefix)
+		}
+		var err error
+		if config, err = cfg.LoadConfig(configFile); err != nil {
+			return nil, err
+		}
+	}
+	config.ApplyDefaults()
+	cfg.SetCurrent(config)
+	return config, nil
+}
 
-	engine := gin.New()
+// 监听配置文件变化并重新加载，无效的配置不会生效
+func watchConfig(ctx context.Context) error {
+	return cfg.Watch(ctx, func(config *cfg.AppConfig, err error) {
+		if err != nil {
+			log.Error(ctx, "Reload config failed, keep the current config, error: %v", err)
+			return
+		}
+		log.Info(ctx, "Config reloaded from %s", strings.Join(config.Layers(), ","))
+	})
+}
 
-	// 性能监控中间件
-	// to look at the heap profile: go tool ip:port/dev/pprof/heap
-	// to look at a 30-second CPU profile: go tool ip:port/dev/pprof/profile
-	// to look at the goroutine blocking profile: go tool ip:port/dev/pprof/block
-	// to collect a 5-second execution trace: wget ip:port/debug/pprof/trace?seconds=5
-	pprof.Register(engine, "dev/pprof")
+// 初始化组件，未配置的组件直接跳过
+func initComponent(config *cfg.AppConfig, component Component) error {
+	switch component {
+	case ComponentMysql:
+		return db.InitMysql(config)
+	case ComponentRedis:
+		if err := cache.InitRedis(config); err != nil {
+			return err
+		}
+		return cache.InitRedisCluster(config)
+	case ComponentMongo:
+		return mongo.InitMongo(config)
+	case ComponentKafka:
+		if err := kafka.InitProducer(config); err != nil {
+			return err
+		}
+		return kafka.InitConsumer(config)
+	default:
+		return fmt.Errorf("unknown component %s", component)
+	}
+}
 
-	engine.Use(middleware.Trace())
-	engine.Use(middleware.Logger())
-	engine.Use(gin.CustomRecovery(func(c *gin.Context, err interface{}) {
-		log.Error(c, "panic recovery: %v", err)
-		c.AbortWithStatusJSON(http.StatusOK, gin.H{"code": -1, "msg": "服务器异常，请稍后再试"})
-	}))
+// 组件对应的健康检查项
+func componentCheckers(component Component) []health.Checker {
+	var checkers []health.Checker
+	switch component {
+	case ComponentMysql:
+		for _, name := range db.Names() {
+			name := name
+			checkers = append(checkers, health.NewChecker("mysql:"+name, func(ctx context.Context) error {
+				return db.Ping(ctx, name)
+			}))
+		}
+	case ComponentRedis:
+		if cache.Configured() {
+			checkers = append(checkers, health.NewChecker("redis", cache.Ping))
+		}
+	case ComponentMongo:
+		if mongo.Configured() {
+			checkers = append(checkers, health.NewChecker("mongo", mongo.Ping))
+		}
+	case ComponentKafka:
+		for _, broker := range kafka.Brokers() {
+			broker := broker
+			checkers = append(checkers, health.NewChecker("kafka:"+broker, func(ctx context.Context) error {
+				return kafka.Ping(ctx, broker)
+			}))
+		}
+	}
+	return checkers
+}
 
-	registerRoutes(engine)
+// 组件对应的连接池指标
+func componentCollectors(component Component) []prometheus.Collector {
+	var result []prometheus.Collector
+	switch component {
+	case ComponentMysql:
+		for _, name := range db.Names() {
+			name := name
+			result = append(result, metrics.NewDBStatsCollector(name, func() (*sql.DB, bool) {
+				return db.Opened(name)
+			}))
+		}
+	case ComponentRedis:
+		if cache.Configured() {
+			result = append(result, metrics.NewRedisPoolCollector(cache.PoolStats))
+		}
+	}
+	return result
+}
 
-	return engine
+// 关闭组件
+func closeComponent(component Component) {
+	switch component {
+	case ComponentMysql:
+		db.Close()
+	case ComponentRedis:
+		cache.Close()
+	case ComponentMongo:
+		mongo.Close()
+	case ComponentKafka:
+		kafka.Close()
+	}
+}
+
+// Container 获取依赖容器，基础组件以及db.DB、cache.Redis等全局方法均使用该容器
+func (app *App) Container() *container.Container {
+	return container.Default
+}
+
+// Config 获取应用配置
+func (app *App) Config() *cfg.AppConfig {
+	return app.config
+}
+
+// Engine 获取gin路由
+func (app *App) Engine() *gin.Engine {
+	return app.engine
+}
+
+// RegisterChecker 注册自定义健康检查项
+func (app *App) RegisterChecker(checkers ...health.Checker) {
+	health.Register(checkers...)
+}
+
+// RegisterWorker 注册后台任务，任务在http服务启动后运行，应用停止时取消ctx并等待任务退出
+func (app *App) RegisterWorker(name string, fn worker.Func, opts ...worker.Option) error {
+	if err := app.workers.Register(name, fn, opts...); err != nil {
+		return err
+	}
+	health.Register(health.NewChecker("worker:"+name, func(ctx context.Context) error {
+		return app.workers.Check(name)
+	}))
+	return nil
 }
 
-// 监听系统命令
-func listenToSystemSignals(cancel context.CancelFunc) {
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+// Run 启动服务并阻塞，直到ctx取消、收到系统退出信号或服务异常退出
+func (app *App) Run(ctx context.Context) error {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := app.runStartHooks(ctx); err != nil {
+		return errors.Join(err, app.Stop(context.Background()))
+	}
+	app.logBanner()
+	// 由平滑升级启动时通知父进程退出
+	if err := upgrade.Ready(); err != nil {
+		log.Error(context.Background(), "Notify parent process ready failed, error: %v", err)
+	}
+
+	upgradeChan := make(chan os.Signal, 1)
+	if signals := upgrade.Signals(); app.config.GracefulUpgrade && len(signals) > 0 {
+		signal.Notify(upgradeChan, signals...)
+		defer signal.Stop(upgradeChan)
+	}
+
+	var serveErr error
+loop:
 	for {
 		select {
-		case <-signalChan:
-			cancel()
-			return
+		case serveErr = <-app.serveErr:
+			break loop
+		case <-ctx.Done():
+			break loop
+		case <-upgradeChan:
+			if err := upgrade.Upgrade(); err != nil {
+				log.Error(context.Background(), "Graceful upgrade failed, error: %v", err)
+				continue
+			}
+			log.Info(context.Background(), "Graceful upgrade success, new process is ready, start draining")
+			break loop
 		}
 	}
+
+	err := errors.Join(serveErr, app.Stop(context.Background()))
+	if err == nil {
+		log.Info(context.Background(), "Server was shutdown gracefully")
+	}
+	return err
 }
 
-// 关闭端口
-func shutdown(server *http.Server) {
-	time.Sleep(5 * time.Second)
-	// 最后释放log
-	defer func() {
-		if err := log.Logger.Sync(); err != nil {
-			fmt.Printf("Failed to close logger: %s\n", err)
+// 输出启动信息：构建版本、监听地址以及启用的组件
+func (app *App) logBanner() {
+	info := buildinfo.Get()
+	components := make([]string, 0, len(app.opts.components))
+	for _, component := range app.opts.components {
+		components = append(components, string(component))
+	}
+	scheme := "http"
+	if app.server.TLSConfig != nil {
+		scheme = "https"
+	}
+	admin, grpcAddr := "disabled", "disabled"
+	if app.adminServer != nil {
+		admin = app.adminServer.Addr
+	}
+	if app.grpcServer != nil {
+		grpcAddr = app.config.GrpcAddr + ":" + strconv.Itoa(app.config.GrpcPort)
+		if app.grpcMux != nil {
+			grpcAddr = "shared with " + scheme
 		}
-		if err := log.AccessLogger.Sync(); err != nil {
-			fmt.Printf("Failed to close access logger: %s\n", err)
+	}
+	log.Info(context.Background(), "Server started success, version: %s, commit: %s, build time: %s, go: %s, env: %s, "+
+		"%s: %s, admin: %s, grpc: %s, components: [%s]",
+		info.Version, info.Commit, info.BuildTime, info.GoVersion, app.config.Env,
+		scheme, app.server.Addr, admin, grpcAddr, strings.Join(components, ","))
+}
+
+// Stop 按顺序执行停止钩子并释放资源，多次调用只会执行一次
+func (app *App) Stop(ctx context.Context) error {
+	app.stopOnce.Do(func() {
+		// 立即让readiness检查失败，负载均衡尽快摘除流量
+		health.MarkShuttingDown()
+		app.stopErr = app.runStopHooks(ctx)
+		app.cancel()
+	})
+	return app.stopErr
+}
+
+// 启动http服务，监听端口失败时直接返回错误
+func (app *App) serve(server *http.Server) error {
+	listener, err := upgrade.Listen(server.Addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		var serveErr error
+		if server.TLSConfig != nil {
+			serveErr = server.ServeTLS(listener, "", "")
+		} else {
+			serveErr = server.Serve(listener)
+		}
+		if serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+			app.serveErr <- serveErr
 		}
 	}()
+	return nil
+}
 
-	// 资源释放
-	cache.Close()
-	kafka.Close()
-	log.Close()
+// 启动独立端口的grpc服务
+func (app *App) serveGrpc() error {
+	listener, err := upgrade.Listen(app.config.GrpcAddr + ":" + strconv.Itoa(app.config.GrpcPort))
+	if err != nil {
+		return err
+	}
+	if app.certReloader != nil {
+		listener = tls.NewListener(listener, app.certReloader.TLSConfig())
+	}
+	go func() {
+		if err := app.grpcServer.Serve(listener); err != nil {
+			app.serveErr <- err
+		}
+	}()
+	return nil
+}
 
-	// 关闭server
-	if err := server.Shutdown(context.Background()); err != nil {
-		log.Error(context.Background(), "Shutdown server failed, error: %v", err)
+// 排空http请求，超时后强制关闭剩余连接
+func drain(ctx context.Context, server *http.Server) error {
+	if err := server.Shutdown(ctx); err != nil {
+		_ = server.Close()
+		return err
 	}
+	return nil
+}
+
+// 初始化gin路由
+func initEngine(cfg *cfg.AppConfig, components []Component, registerRoutes func(*gin.Engine), registerAdminRoutes func(*gin.RouterGroup),
+	recoveryHandler func(*gin.Context, interface{})) *gin.Engine {
+	gin.SetMode(func() string {
+		if cfg.IsDevEnv() {
+			return gin.DebugMode
+		}
+		return gin.ReleaseMode
+	}())
+
+	engine := gin.New()
+	recovery := middleware.Recovery(middleware.RecoveryConfig{
+		Dev:     cfg.IsDevEnv(),
+		Handler: recoveryHandler,
+	})
+
+	// 未启用独立管理端口时，运维接口仍注册在业务端口上，只使用trace与recovery，不记录访问日志也不参与限流
+	if !cfg.AdminEnabled() {
+		registerOpsRoutes(cfg, components, engine.Group("", middleware.Trace(), recovery), registerAdminRoutes, false)
+	}
+
+	engine.Use(middleware.Trace())
+	engine.Use(middleware.Logger())
+	engine.Use(middleware.Metrics())
+	if cfg.LimiterEnabled() {
+		engine.Use(middleware.Shed(cfg))
+	}
+	if cfg.TlsClientCaFile != "" {
+		engine.Use(middleware.ClientCert())
+	}
+	engine.Use(recovery)
+
+	registerRoutes(engine)
+
+	return engine
 }
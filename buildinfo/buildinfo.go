@@ -0,0 +1,81 @@
+// Package buildinfo 应用的构建信息，优先使用ldflags注入的值，未注入时从runtime/debug.ReadBuildInfo读取，如：
+//
+//	go build -ldflags "-X github.com/easonchen147/foundation/buildinfo.Version=v1.2.0 \
+//		-X github.com/easonchen147/foundation/buildinfo.Commit=$(git rev-parse HEAD) \
+//		-X github.com/easonchen147/foundation/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// 通过ldflags注入
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+// Info 构建信息
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	Modified  bool   `json:"modified"` // 构建时工作区是否有未提交的修改
+	Module    string `json:"module"`
+	GoVersion string `json:"goVersion"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get 获取构建信息
+func Get() Info {
+	once.Do(func() {
+		info = Info{
+			Version:   Version,
+			Commit:    Commit,
+			BuildTime: BuildTime,
+			GoVersion: runtime.Version(),
+		}
+		if buildInfo, ok := debug.ReadBuildInfo(); ok {
+			info.Module = buildInfo.Main.Path
+			if info.Version == "" {
+				info.Version = buildInfo.Main.Version
+			}
+			for _, setting := range buildInfo.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					if info.Commit == "" {
+						info.Commit = setting.Value
+					}
+				case "vcs.time":
+					if info.BuildTime == "" {
+						info.BuildTime = setting.Value
+					}
+				case "vcs.modified":
+					info.Modified = setting.Value == "true"
+				}
+			}
+		}
+		if info.Version == "" {
+			info.Version = "unknown"
+		}
+		if info.Commit == "" {
+			info.Commit = "unknown"
+		}
+	})
+	return info
+}
+
+// ShortCommit 获取前12位的commit
+func (i Info) ShortCommit() string {
+	if len(i.Commit) > 12 {
+		return i.Commit[:12]
+	}
+	return i.Commit
+}
@@ -1,87 +1,129 @@
 package cache
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/easonchen147/foundation/cfg"
+	"github.com/easonchen147/foundation/container"
 
 	"github.com/redis/go-redis/v9"
 )
 
-var (
-	client        *redis.Client
-	clusterClient *redis.ClusterClient
-)
-
-func init() {
-	err := InitRedis(cfg.AppConf)
-	if err != nil {
-		panic(fmt.Sprintf("init redis failed: %s", err))
-	}
-
-	err = InitRedisCluster(cfg.AppConf)
-	if err != nil {
-		panic(fmt.Sprintf("init redis cluster failed: %s", err))
-	}
-}
-
-// InitRedis 初始化redis
+// InitRedis 注册redis，客户端在首次使用时创建
 func InitRedis(cfg *cfg.AppConfig) error {
 	if cfg.RedisConfig == nil {
 		return nil
 	}
-	client = redis.NewClient(&redis.Options{
-		Addr:         cfg.RedisConfig.Addr,
-		Username:     cfg.RedisConfig.User,
-		Password:     cfg.RedisConfig.Pass,
-		DB:           cfg.RedisConfig.Db,
-		MinIdleConns: cfg.RedisConfig.MinIdle,
-		PoolSize:     cfg.RedisConfig.PoolSize,
-		DialTimeout:  time.Second * time.Duration(cfg.RedisConfig.ConnectTimeout),
-		ReadTimeout:  time.Second * time.Duration(cfg.RedisConfig.ReadTimeout),
-		WriteTimeout: time.Second * time.Duration(cfg.RedisConfig.WriteTimeout),
+	container.Provide(container.Default, "", func() (*redis.Client, error) {
+		return redis.NewClient(&redis.Options{
+			Addr:         cfg.RedisConfig.Addr,
+			Username:     cfg.RedisConfig.User,
+			Password:     cfg.RedisConfig.Pass,
+			DB:           cfg.RedisConfig.Db,
+			MinIdleConns: cfg.RedisConfig.MinIdle,
+			PoolSize:     cfg.RedisConfig.PoolSize,
+			DialTimeout:  time.Second * time.Duration(cfg.RedisConfig.ConnectTimeout),
+			ReadTimeout:  time.Second * time.Duration(cfg.RedisConfig.ReadTimeout),
+			WriteTimeout: time.Second * time.Duration(cfg.RedisConfig.WriteTimeout),
+		}), nil
 	})
 	return nil
 }
 
+// Redis 获取redis客户端，未配置时panic，需要处理错误时使用GetRedis
 func Redis() *redis.Client {
-	if client == nil {
-		panic(errors.New("cache is not ready"))
+	client, err := GetRedis()
+	if err != nil {
+		panic(fmt.Errorf("cache is not ready: %w", err))
 	}
 	return client
 }
 
-// InitRedisCluster 初始化redis cluster
+// GetRedis 获取redis客户端
+func GetRedis() (*redis.Client, error) {
+	return container.Resolve[*redis.Client](container.Default, "")
+}
+
+// InitRedisCluster 注册redis cluster，客户端在首次使用时创建
 func InitRedisCluster(cfg *cfg.AppConfig) error {
 	if cfg.RedisClusterConfig == nil {
 		return nil
 	}
-	clusterClient = redis.NewClusterClient(&redis.ClusterOptions{
-		Addrs:        cfg.RedisClusterConfig.Addrs,
-		Password:     cfg.RedisClusterConfig.Pass,
-		MinIdleConns: cfg.RedisClusterConfig.MinIdle,
-		PoolSize:     cfg.RedisClusterConfig.PoolSize,
-		DialTimeout:  time.Second * time.Duration(cfg.RedisConfig.ConnectTimeout),
-		ReadTimeout:  time.Second * time.Duration(cfg.RedisConfig.ReadTimeout),
-		WriteTimeout: time.Second * time.Duration(cfg.RedisConfig.WriteTimeout),
+	container.Provide(container.Default, "", func() (*redis.ClusterClient, error) {
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        cfg.RedisClusterConfig.Addrs,
+			Password:     cfg.RedisClusterConfig.Pass,
+			MinIdleConns: cfg.RedisClusterConfig.MinIdle,
+			PoolSize:     cfg.RedisClusterConfig.PoolSize,
+			DialTimeout:  time.Second * time.Duration(cfg.RedisClusterConfig.ConnectTimeout),
+			ReadTimeout:  time.Second * time.Duration(cfg.RedisClusterConfig.ReadTimeout),
+			WriteTimeout: time.Second * time.Duration(cfg.RedisClusterConfig.WriteTimeout),
+		}), nil
 	})
 	return nil
 }
 
+// RedisCluster 获取redis cluster客户端，未配置时panic，需要处理错误时使用GetRedisCluster
 func RedisCluster() *redis.ClusterClient {
-	if clusterClient == nil {
-		panic(errors.New("foundation cluster is not ready"))
+	clusterClient, err := GetRedisCluster()
+	if err != nil {
+		panic(fmt.Errorf("foundation cluster is not ready: %w", err))
 	}
 	return clusterClient
 }
 
+// GetRedisCluster 获取redis cluster客户端
+func GetRedisCluster() (*redis.ClusterClient, error) {
+	return container.Resolve[*redis.ClusterClient](container.Default, "")
+}
+
+// Ping 检查已配置的redis及redis cluster是否可用，尚未创建客户端时先创建
+func Ping(ctx context.Context) error {
+	if container.Has[*redis.Client](container.Default, "") {
+		client, err := GetRedis()
+		if err != nil {
+			return err
+		}
+		if err = client.Ping(ctx).Err(); err != nil {
+			return err
+		}
+	}
+	if container.Has[*redis.ClusterClient](container.Default, "") {
+		clusterClient, err := GetRedisCluster()
+		if err != nil {
+			return err
+		}
+		if err = clusterClient.Ping(ctx).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PoolStats 获取已创建客户端的连接池状态，key为客户端名称
+func PoolStats() map[string]*redis.PoolStats {
+	stats := make(map[string]*redis.PoolStats)
+	if client, ok := container.Resolved[*redis.Client](container.Default, ""); ok {
+		stats["redis"] = client.PoolStats()
+	}
+	if clusterClient, ok := container.Resolved[*redis.ClusterClient](container.Default, ""); ok {
+		stats["redis_cluster"] = clusterClient.PoolStats()
+	}
+	return stats
+}
+
+// Configured 是否已配置redis或redis cluster
+func Configured() bool {
+	return container.Has[*redis.Client](container.Default, "") || container.Has[*redis.ClusterClient](container.Default, "")
+}
+
 func Close() {
-	if client != nil {
+	if client, ok := container.Resolved[*redis.Client](container.Default, ""); ok {
 		_ = client.Close()
 	}
-	if clusterClient != nil {
+	if clusterClient, ok := container.Resolved[*redis.ClusterClient](container.Default, ""); ok {
 		_ = clusterClient.Close()
 	}
 }
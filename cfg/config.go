@@ -4,30 +4,50 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
 const (
 	Dev  = "dev"
+	Test = "test"
 	Qa   = "qa"
 	Prod = "prod"
 )
 
+// 配置项的来源
+const (
+	SourceDefault  = "default"  // 未设置，使用默认值
+	SourceSettings = "settings" // 通过LoadConfigMap设置
+)
+
 var AppConf *AppConfig
 var gloablViper *viper.Viper
 
 type AppConfig struct {
 	File string
 
-	Env           string `mapstructure:"env"`
-	HttpAddr      string `mapstructure:"http_addr"`
-	HttpPort      int    `mapstructure:"http_port"`
-	LogMode       string `mapstructure:"log_mode"`
-	LogFile       string `mapstructure:"log_file"`
-	LogLevel      string `mapstructure:"log_level"`
-	AccessLogFile string `mapstructure:"access_log_file"`
-	SqlLogFile    string `mapstructure:"sql_log_file"`
+	Env             string `mapstructure:"env"`
+	HttpAddr        string `mapstructure:"http_addr"`
+	HttpPort        int    `mapstructure:"http_port"`
+	LogMode         string `mapstructure:"log_mode"`
+	LogFile         string `mapstructure:"log_file"`
+	LogLevel        string `mapstructure:"log_level"`
+	AccessLogFile   string `mapstructure:"access_log_file"`
+	SqlLogFile      string `mapstructure:"sql_log_file"`
+	TlsCertFile     string `mapstructure:"tls_cert_file"`
+	TlsKeyFile      string `mapstructure:"tls_key_file"`
+	TlsClientCaFile string `mapstructure:"tls_client_ca_file"` // 配置后要求客户端提供由该CA签发的证书
+	GrpcAddr        string `mapstructure:"grpc_addr"`
+	GrpcPort        int    `mapstructure:"grpc_port"` // 为0或与http_port相同时与http复用端口
+	AdminAddr       string `mapstructure:"admin_addr"`
+	AdminPort       int    `mapstructure:"admin_port"` // 大于0时启用独立的管理端口
+
+	GracefulUpgrade bool `mapstructure:"graceful_upgrade"` // 仅linux，收到SIGUSR2时启动新进程并传递监听socket
+	ConfigWatch     bool `mapstructure:"config_watch"`     // 监听配置文件变化并重新加载，ts、sign、log_level等配置无需重启即可生效
 
 	DbsConfig          map[string]*dbConfig `mapstructure:"dbs"`
 	MongoConfig        *mongoConfig         `mapstructure:"mongo"`
@@ -36,24 +56,36 @@ type AppConfig struct {
 	KafkaConfig        *kafkaConfig         `mapstructure:"kafka"`
 	SignConfig         *signConfig          `mapstructure:"sign"`
 	TsConfig           *tsConfig            `mapstructure:"ts"`
+	ShutdownConfig     *shutdownConfig      `mapstructure:"shutdown"`
+	AdminConfig        *adminConfig         `mapstructure:"admin"`
+	ServerConfig       *serverConfig        `mapstructure:"server"`
+	LimiterConfig      *limiterConfig       `mapstructure:"limiter"`
 
 	HttpTimeout int `mapstructure:"http_timeout"` // second，default 5s
 
 	Ext map[string]interface{} `mapstructure:"ext"`
+
+	layers  []string            // 按合并顺序加载的配置文件
+	sources map[string]string   // 配置项来源的配置文件
+	viper   *viper.Viper        // 合并后的配置，用于读取ext配置
+	secrets map[string]struct{} // 由密钥引用或加密值解析得到的配置项
+
+	unknownEnv []string // 无法对应到配置项的环境变量
 }
 
 type dbConfig struct {
-	Uri             string `mapstructure:"uri"`
+	Uri             string `mapstructure:"uri" secret:"uri"`
 	MaxIdleConn     int    `mapstructure:"max_idle_conn"`
 	MaxOpenConn     int    `mapstructure:"max_open_conn"`
 	ConnectIdleTime int    `mapstructure:"connect_idle_time"` //second default 300s
 	ConnectLifeTime int    `mapstructure:"connect_life_time"` //second default 600s
+	ConnectTimeout  int    `mapstructure:"connect_timeout"`   //second default 5s, uri中设置了timeout时以uri为准
 }
 
 type redisConfig struct {
 	Addr           string `mapstructure:"addr"`
 	User           string `mapstructure:"user"`
-	Pass           string `mapstructure:"pass"`
+	Pass           string `mapstructure:"pass" secret:"true"`
 	Db             int    `mapstructure:"db"`
 	MinIdle        int    `mapstructure:"min_idle"`
 	PoolSize       int    `mapstructure:"pool_size"`
@@ -64,7 +96,7 @@ type redisConfig struct {
 
 type redisClusterConfig struct {
 	Addrs          []string `mapstructure:"addrs"`
-	Pass           string   `mapstructure:"pass"`
+	Pass           string   `mapstructure:"pass" secret:"true"`
 	MinIdle        int      `mapstructure:"min_idle"`
 	PoolSize       int      `mapstructure:"pool_size"`
 	ConnectTimeout int      `mapstructure:"connect_timeout"` //second default not set
@@ -90,83 +122,317 @@ type kafkaProducerConfig struct {
 }
 
 type mongoConfig struct {
-	Uri            string `mapstructure:"uri"`
+	Uri            string `mapstructure:"uri" secret:"uri"`
 	Db             string `mapstructure:"db"`
-	ConnectTimeout uint64 `mapstructure:"connect_timeout"`
+	ConnectTimeout uint64 `mapstructure:"connect_timeout"` //second default 5s
 	MaxOpenConn    uint64 `mapstructure:"max_open_conn"`
 	MaxPoolSize    uint64 `mapstructure:"max_pool_size"`
 	MinPoolSize    uint64 `mapstructure:"min_pool_size"`
 }
 
 type signConfig struct {
-	Secret string `mapstructure:"secret"`
-	Salt   string `mapstructure:"salt"`
+	Secret string `mapstructure:"secret" secret:"true"`
+	Salt   string `mapstructure:"salt" secret:"true"`
 }
 
 type tsConfig struct {
 	Expire string `mapstructure:"expire"`
 }
 
+type adminConfig struct {
+	User     string   `mapstructure:"user"`
+	Pass     string   `mapstructure:"pass" secret:"true"`
+	AllowIps []string `mapstructure:"allow_ips"` //支持ip或cidr，为空时不限制
+}
+
+type shutdownConfig struct {
+	WaitBeforeDrain int `mapstructure:"wait_before_drain"` //second default 0，停止接收流量后等待负载均衡摘除的时间
+	HttpTimeout     int `mapstructure:"http_timeout"`      //second default 10，排空http请求的超时时间
+	WorkerTimeout   int `mapstructure:"worker_timeout"`    //second default 10，停止后台任务的超时时间
+	ClientTimeout   int `mapstructure:"client_timeout"`    //second default 5，关闭数据客户端的超时时间
+	HookTimeout     int `mapstructure:"hook_timeout"`      //second default 5，其他钩子的默认超时时间
+}
+
+type serverConfig struct {
+	ReadHeaderTimeout int `mapstructure:"read_header_timeout"` //second default 10
+	ReadTimeout       int `mapstructure:"read_timeout"`        //second default 0，不限制
+	WriteTimeout      int `mapstructure:"write_timeout"`       //second default 0，不限制，长连接推送接口需保持为0
+	IdleTimeout       int `mapstructure:"idle_timeout"`        //second default 60
+	MaxHeaderBytes    int `mapstructure:"max_header_bytes"`    //default 1MB
+}
+
+type limiterConfig struct {
+	MaxInflight      int            `mapstructure:"max_inflight"`       //全局最大并发请求数，为0时不限制
+	RouteMaxInflight map[string]int `mapstructure:"route_max_inflight"` //按路由模板限制并发请求数，如"/api/users/:id" = 100
+	Adaptive         string         `mapstructure:"adaptive"`           //自适应算法aimd或gradient，为空时使用固定上限
+	MinLimit         int            `mapstructure:"min_limit"`          //自适应上限的下限，default 10
+	LatencyThreshold int            `mapstructure:"latency_threshold"`  //millisecond default 1000，aimd算法中超过该耗时时降低上限
+	RetryAfter       int            `mapstructure:"retry_after"`        //second default 1，拒绝请求时返回的Retry-After
+}
+
 func InitConfig(file string) *AppConfig {
-	AppConf = &AppConfig{
+	AppConf = newConfig(file)
+	return AppConf
+}
+
+// 创建填充了默认值的配置
+func newConfig(file string) *AppConfig {
+	config := &AppConfig{
 		File:          file,
 		Env:           Dev,
 		HttpAddr:      "0.0.0.0",
 		HttpPort:      8080,
+		GrpcAddr:      "0.0.0.0",
+		AdminAddr:     "0.0.0.0",
 		LogMode:       "console",
 		LogFile:       "logs/app.log",
 		LogLevel:      "debug",
 		AccessLogFile: "logs/access.log",
 		HttpTimeout:   5,
 	}
-	return AppConf
+	config.ApplyDefaults()
+	return config
 }
 
-// load 加载toml配置文件内容
+// ApplyDefaults 为未配置的配置段填充默认值
+func (cfg *AppConfig) ApplyDefaults() {
+	if cfg.ShutdownConfig == nil {
+		cfg.ShutdownConfig = &shutdownConfig{
+			HttpTimeout:   10,
+			WorkerTimeout: 10,
+			ClientTimeout: 5,
+			HookTimeout:   5,
+		}
+	}
+	if cfg.ServerConfig == nil {
+		cfg.ServerConfig = &serverConfig{
+			ReadHeaderTimeout: 10,
+			IdleTimeout:       60,
+		}
+	}
+}
+
+// LimiterEnabled 是否启用并发限制
+func (cfg *AppConfig) LimiterEnabled() bool {
+	return cfg.LimiterConfig != nil && (cfg.LimiterConfig.MaxInflight > 0 || len(cfg.LimiterConfig.RouteMaxInflight) > 0)
+}
+
+// load 按顺序加载并合并配置文件：基础配置、环境配置(如app.prod.toml)、本地配置(如app.local.toml)，最后以环境变量覆盖，
+// 合并后解析配置值中的${env:NAME}、${file:path}引用以及enc:开头的加密值，
+// 环境由基础配置中的env或环境变量FOUNDATION_ENV决定，环境配置与本地配置不存在时跳过
 func (cfg *AppConfig) load() error {
 	if _, err := os.Stat(cfg.File); os.IsNotExist(err) {
 		return fmt.Errorf("config file %s not existed", cfg.File)
 	}
 
-	// 全局唯一的viper
-	gloablViper = viper.New()
-	gloablViper.SetConfigFile(cfg.File)
-	gloablViper.SetConfigType("toml")
+	var overrides []envOverride
+	overrides, cfg.unknownEnv = envOverrides(envPrefix, os.Environ())
 
-	if err := gloablViper.ReadInConfig(); err != nil {
-		return fmt.Errorf("load config file %s failed, error: %v", cfg.File, err)
+	cfg.viper = viper.New()
+	cfg.sources = make(map[string]string)
+	cfg.layers = nil
+	if err := cfg.merge(cfg.File); err != nil {
+		return err
+	}
+	env := cfg.viper.GetString("env")
+	if value, ok := envOverrideOf(overrides, "env"); ok {
+		env = value
+	}
+	if env == "" {
+		env = cfg.Env
+	}
+	for _, file := range overlayFiles(cfg.File, env) {
+		if err := cfg.merge(file); err != nil {
+			return err
+		}
+	}
+	if err := cfg.applyEnv(overrides); err != nil {
+		return err
 	}
-	if err := gloablViper.Unmarshal(&cfg); err != nil {
-		return fmt.Errorf("unmarshal %s to config object failed, error: %v", cfg.File, err)
+	if err := cfg.resolveSecrets(); err != nil {
+		return err
+	}
+
+	if err := cfg.viper.Unmarshal(&cfg); err != nil {
+		return fmt.Errorf("unmarshal %s to config object failed, error: %v", strings.Join(cfg.layers, ","), err)
 	}
 	return nil
 }
 
+// merge 读取单个配置文件并合并到全局配置，记录每个key的来源
+func (cfg *AppConfig) merge(file string) error {
+	configType, err := configTypeOf(file)
+	if err != nil {
+		return err
+	}
+	layer := viper.New()
+	layer.SetConfigFile(file)
+	layer.SetConfigType(configType)
+	if err := layer.ReadInConfig(); err != nil {
+		return fmt.Errorf("load config file %s failed, error: %v", file, err)
+	}
+	if err := cfg.viper.MergeConfigMap(layer.AllSettings()); err != nil {
+		return fmt.Errorf("merge config file %s failed, error: %v", file, err)
+	}
+	for _, key := range layer.AllKeys() {
+		cfg.sources[key] = file
+	}
+	cfg.layers = append(cfg.layers, file)
+	return nil
+}
+
+// 根据扩展名获取配置格式
+func configTypeOf(file string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(file)); ext {
+	case ".toml":
+		return "toml", nil
+	case ".yaml", ".yml":
+		return "yaml", nil
+	case ".json":
+		return "json", nil
+	default:
+		return "", fmt.Errorf("unsupported config format %q of %s, use .toml, .yaml, .yml or .json", ext, file)
+	}
+}
+
+// 获取存在的环境配置与本地配置，优先使用与基础配置相同的格式
+func overlayFiles(base, env string) []string {
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext)
+	exts := []string{ext}
+	for _, candidate := range configExts {
+		if !strings.EqualFold(candidate, ext) {
+			exts = append(exts, candidate)
+		}
+	}
+
+	var files []string
+	for _, layer := range []string{env, "local"} {
+		if layer == "" {
+			continue
+		}
+		for _, candidate := range exts {
+			file := prefix + "." + layer + candidate
+			if info, err := os.Stat(file); err == nil && !info.IsDir() {
+				files = append(files, file)
+				break
+			}
+		}
+	}
+	return files
+}
+
+var configExts = []string{".toml", ".yaml", ".yml", ".json"}
+
+// Layers 按合并顺序返回已加载的配置文件
+func (cfg *AppConfig) Layers() []string {
+	return append([]string(nil), cfg.layers...)
+}
+
+// Source 获取配置项的来源文件，key为"redis.addr"形式的路径，未在配置文件中设置时返回"default"
+func (cfg *AppConfig) Source(key string) string {
+	if source, ok := cfg.sources[strings.ToLower(key)]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+// Sources 获取配置文件中所有配置项的来源文件
+func (cfg *AppConfig) Sources() map[string]string {
+	sources := make(map[string]string, len(cfg.sources))
+	for key, source := range cfg.sources {
+		sources[key] = source
+	}
+	return sources
+}
+
+// TlsEnabled 是否启用https
+func (cfg *AppConfig) TlsEnabled() bool {
+	return cfg.TlsCertFile != "" && cfg.TlsKeyFile != ""
+}
+
+// GrpcStandalone grpc服务是否使用独立端口
+func (cfg *AppConfig) GrpcStandalone() bool {
+	return cfg.GrpcPort > 0 && cfg.GrpcPort != cfg.HttpPort
+}
+
+// AdminEnabled 是否启用独立的管理端口
+func (cfg *AppConfig) AdminEnabled() bool {
+	return cfg.AdminPort > 0
+}
+
 func (cfg *AppConfig) IsDevEnv() bool {
 	return cfg.Env == "dev"
 }
 
 func (cfg *AppConfig) LoadExtConfig(v interface{}) error {
-	if gloablViper == nil {
+	global := cfg.viper
+	if global == nil {
+		global = gloablViper
+	}
+	if global == nil {
 		return errors.New("global viper is not initialize")
 	}
-	extV := gloablViper.Sub("ext")
+	extV := global.Sub("ext")
 	if extV == nil {
 		return nil
 	}
 	return extV.Unmarshal(&v)
 }
 
-func init() {
-	configFile := "app.toml"
+// DefaultConfigFile 默认配置文件路径，可通过环境变量CONFIG_FILE指定，否则依次查找app.toml、app.yaml、app.yml、app.json
+func DefaultConfigFile() string {
 	if envFilePath := os.Getenv("CONFIG_FILE"); envFilePath != "" {
-		configFile = envFilePath
+		return envFilePath
+	}
+	for _, ext := range configExts {
+		if _, err := os.Stat("app" + ext); err == nil {
+			return "app" + ext
+		}
+	}
+	return "app.toml"
+}
+
+// LoadConfig 加载配置文件及对应的环境配置、本地配置，并设置为全局配置，支持toml、yaml、json格式
+func LoadConfig(file string) (*AppConfig, error) {
+	cfg := InitConfig(file)
+	if err := cfg.load(); err != nil {
+		return nil, fmt.Errorf("load config failed, file: %s, error: %s", file, err)
 	}
+	gloablViper = cfg.viper
+	SetCurrent(cfg)
+	return cfg, nil
+}
 
-	// 加载配置
-	cfg := InitConfig(configFile)
-	err := cfg.load()
-	if err != nil {
-		panic(fmt.Sprintf("load config failed, file: %s, error: %s", configFile, err))
+// LoadConfigMap 使用内存中的配置项创建配置，key可以是"redis.addr"形式的路径或嵌套的map，并设置为全局配置
+func LoadConfigMap(settings map[string]interface{}) (*AppConfig, error) {
+	cfg := InitConfig("")
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	// 按key排序保证"redis"与"redis.addr"同时存在时结果确定
+	sort.Strings(keys)
+
+	// 使用合并而不是viper.Set，Set的值优先级最高，会覆盖解析后的密钥
+	cfg.viper = viper.New()
+	for _, key := range keys {
+		if err := cfg.set(key, settings[key]); err != nil {
+			return nil, err
+		}
+	}
+	cfg.sources = make(map[string]string)
+	for _, key := range cfg.viper.AllKeys() {
+		cfg.sources[key] = SourceSettings
+	}
+	if err := cfg.resolveSecrets(); err != nil {
+		return nil, err
+	}
+	if err := cfg.viper.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("unmarshal settings to config object failed, error: %v", err)
 	}
+	cfg.ApplyDefaults()
+	gloablViper = cfg.viper
+	SetCurrent(cfg)
+	return cfg, nil
 }
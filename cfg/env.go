@@ -0,0 +1,215 @@
+package cfg
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DefaultEnvPrefix 覆盖配置的环境变量的默认前缀
+const DefaultEnvPrefix = "FOUNDATION"
+
+var envPrefix = DefaultEnvPrefix
+
+// SetEnvPrefix 设置覆盖配置的环境变量前缀，为空时不读取环境变量
+func SetEnvPrefix(prefix string) {
+	envPrefix = strings.TrimSuffix(strings.ToUpper(prefix), "_")
+}
+
+// EnvPrefix 获取覆盖配置的环境变量前缀
+func EnvPrefix() string {
+	return envPrefix
+}
+
+// 环境变量与配置项的对应规则，LoadConfig合并配置文件后以环境变量覆盖，优先级最高：
+//
+//  1. 去掉"FOUNDATION_"前缀后转为小写，按AppConfig的字段(mapstructure标签)匹配，存在多个匹配时最长的字段名优先，
+//     如FOUNDATION_HTTP_PORT -> http_port，FOUNDATION_REDIS_CLUSTER_PASS -> redis_cluster.pass
+//  2. dbs、kafka.producers等以名称为key的配置段，名称为去掉配置段与末尾字段名后剩余的部分，
+//     如FOUNDATION_DBS_DEFAULT_URI -> dbs.default.uri，FOUNDATION_KAFKA_PRODUCERS_ORDER_EVENTS_BROKER -> kafka.producers.order_events.broker
+//  3. ext等自由配置段，剩余部分整体作为key，如FOUNDATION_EXT_FEATURE_FLAG -> ext.feature_flag
+//  4. 使用双下划线时按双下划线拆分为完整路径，不再按字段匹配，用于嵌套的ext配置，如FOUNDATION_EXT__PAY__APP_ID -> ext.pay.app_id
+//  5. 数组类型的配置项以逗号分隔，如FOUNDATION_REDIS_CLUSTER_ADDRS=a:6379,b:6379
+//
+// 带前缀但无法对应到配置项的环境变量会被跳过，可通过AppConfig.UnknownEnv获取，应用启动时以warn日志输出，
+// 如k8s为名为foundation的Service注入的FOUNDATION_SERVICE_HOST
+type envOverride struct {
+	name  string // 环境变量名
+	key   string // 配置项路径，如dbs.default.uri
+	value interface{}
+}
+
+// 从环境变量中解析覆盖的配置项，按环境变量名排序，同时返回无法对应到配置项的环境变量名
+func envOverrides(prefix string, environ []string) ([]envOverride, []string) {
+	if prefix == "" {
+		return nil, nil
+	}
+	prefix += "_"
+	schema := reflect.TypeOf(AppConfig{})
+	var overrides []envOverride
+	var unknown []string
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+			continue
+		}
+		rest := strings.ToLower(strings.TrimPrefix(name, prefix))
+
+		var path []string
+		var typ reflect.Type
+		if strings.Contains(rest, "__") {
+			path = strings.Split(rest, "__")
+			typ = typeOfPath(schema, path)
+		} else if path, typ, ok = matchEnvKey(schema, rest); !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		overrides = append(overrides, envOverride{name: name, key: strings.Join(path, "."), value: envValue(typ, value)})
+	}
+	sort.Strings(unknown)
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i].name < overrides[j].name
+	})
+	return overrides, unknown
+}
+
+// UnknownEnv 带前缀但无法对应到配置项而被跳过的环境变量名
+func (cfg *AppConfig) UnknownEnv() []string {
+	return append([]string(nil), cfg.unknownEnv...)
+}
+
+// 将去掉前缀的环境变量名按类型定义匹配为配置项路径
+func matchEnvKey(typ reflect.Type, rest string) ([]string, reflect.Type, bool) {
+	typ = indirect(typ)
+	switch typ.Kind() {
+	case reflect.Struct:
+		// 字段名越长越优先，如redis_cluster_pass优先匹配redis_cluster
+		fields := taggedFields(typ)
+		for _, field := range fields {
+			tag := field.Tag.Get("mapstructure")
+			if rest == tag {
+				if isNested(field.Type) {
+					return nil, nil, false
+				}
+				return []string{tag}, field.Type, true
+			}
+			if !strings.HasPrefix(rest, tag+"_") || !isNested(field.Type) {
+				continue
+			}
+			if path, leaf, ok := matchEnvKey(field.Type, strings.TrimPrefix(rest, tag+"_")); ok {
+				return append([]string{tag}, path...), leaf, true
+			}
+		}
+	case reflect.Map:
+		elem := indirect(typ.Elem())
+		if elem.Kind() != reflect.Struct {
+			return []string{rest}, typ.Elem(), true
+		}
+		// 名称可能包含下划线，取最长的字段名作为末尾字段
+		for _, field := range taggedFields(elem) {
+			tag := field.Tag.Get("mapstructure")
+			if name := strings.TrimSuffix(rest, "_"+tag); name != rest && name != "" && !isNested(field.Type) {
+				return []string{name, tag}, field.Type, true
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+// 按路径获取配置项的类型，自由配置段中无法确定时返回nil
+func typeOfPath(typ reflect.Type, path []string) reflect.Type {
+	for _, segment := range path {
+		if typ == nil {
+			return nil
+		}
+		typ = indirect(typ)
+		switch typ.Kind() {
+		case reflect.Struct:
+			var next reflect.Type
+			for _, field := range taggedFields(typ) {
+				if field.Tag.Get("mapstructure") == segment {
+					next = field.Type
+					break
+				}
+			}
+			typ = next
+		case reflect.Map:
+			typ = typ.Elem()
+		default:
+			return nil
+		}
+	}
+	return typ
+}
+
+// 数组类型以逗号分隔，其他类型由配置反序列化时转换
+func envValue(typ reflect.Type, value string) interface{} {
+	if typ != nil && typ.Kind() == reflect.Slice {
+		items := strings.Split(value, ",")
+		for i := range items {
+			items[i] = strings.TrimSpace(items[i])
+		}
+		return items
+	}
+	return value
+}
+
+// 带mapstructure标签的字段，按标签长度降序排列
+func taggedFields(typ reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.IsExported() && field.Tag.Get("mapstructure") != "" {
+			fields = append(fields, field)
+		}
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		return len(fields[i].Tag.Get("mapstructure")) > len(fields[j].Tag.Get("mapstructure"))
+	})
+	return fields
+}
+
+func isNested(typ reflect.Type) bool {
+	kind := indirect(typ).Kind()
+	return kind == reflect.Struct || kind == reflect.Map
+}
+
+func indirect(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// 环境变量中覆盖的env，用于选择环境配置文件
+func envOverrideOf(overrides []envOverride, key string) (string, bool) {
+	for _, override := range overrides {
+		if override.key == key {
+			value, ok := override.value.(string)
+			return value, ok
+		}
+	}
+	return "", false
+}
+
+// 使用环境变量覆盖配置项，并记录来源
+func (cfg *AppConfig) applyEnv(overrides []envOverride) error {
+	for _, override := range overrides {
+		if err := cfg.set(override.key, override.value); err != nil {
+			return fmt.Errorf("apply env %s failed, error: %v", override.name, err)
+		}
+		cfg.sources[override.key] = "env:" + override.name
+	}
+	return nil
+}
+
+// set 将配置项合并到配置文件的内容中。viper.Set写入的值会覆盖整个上级配置段，导致Sub("ext")等读取不到同级的其他配置项
+func (cfg *AppConfig) set(key string, value interface{}) error {
+	path := strings.Split(key, ".")
+	var nested interface{} = value
+	for i := len(path) - 1; i >= 0; i-- {
+		nested = map[string]interface{}{path[i]: nested}
+	}
+	return cfg.viper.MergeConfigMap(nested.(map[string]interface{}))
+}
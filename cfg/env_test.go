@@ -0,0 +1,103 @@
+package cfg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchEnvKey(t *testing.T) {
+	schema := reflect.TypeOf(AppConfig{})
+	tests := []struct {
+		rest string
+		want string
+		ok   bool
+	}{
+		{"http_port", "http_port", true},
+		{"env", "env", true},
+		{"redis_addr", "redis.addr", true},
+		// 最长的字段名优先：redis_cluster优先于redis
+		{"redis_cluster_pass", "redis_cluster.pass", true},
+		{"redis_cluster_addrs", "redis_cluster.addrs", true},
+		{"dbs_default_uri", "dbs.default.uri", true},
+		// 名称中的下划线保留，取最长的末尾字段名
+		{"dbs_read_replica_max_open_conn", "dbs.read_replica.max_open_conn", true},
+		{"dbs_order_db_connect_idle_time", "dbs.order_db.connect_idle_time", true},
+		{"kafka_producers_order_events_broker", "kafka.producers.order_events.broker", true},
+		{"kafka_consumers_c1_partition", "kafka.consumers.c1.partition", true},
+		{"ext_feature_flag", "ext.feature_flag", true},
+		{"limiter_route_max_inflight_orders", "limiter.route_max_inflight.orders", true},
+		{"redis", "", false},
+		{"dbs_uri", "", false},
+		{"dbs_default", "", false},
+		{"http_prot", "", false},
+		{"listen_fds", "", false},
+		{"service_host", "", false},
+		{"file", "", false},
+	}
+	for _, tt := range tests {
+		path, _, ok := matchEnvKey(schema, tt.rest)
+		if ok != tt.ok || strings.Join(path, ".") != tt.want {
+			t.Errorf("matchEnvKey(%q) = %q, %v, want %q, %v", tt.rest, strings.Join(path, "."), ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	overrides, unknown := envOverrides("APP", []string{
+		"APP_REDIS_CLUSTER_ADDRS=a:1, b:2",
+		"APP_EXT__PAY__APP_ID=42",
+		"APP_HTTP_PORT=9000",
+		"APP_TYPO=1",
+		"APP_=1",
+		"OTHER_HTTP_PORT=1",
+	})
+	want := []envOverride{
+		{name: "APP_EXT__PAY__APP_ID", key: "ext.pay.app_id", value: "42"},
+		{name: "APP_HTTP_PORT", key: "http_port", value: "9000"},
+		{name: "APP_REDIS_CLUSTER_ADDRS", key: "redis_cluster.addrs", value: []string{"a:1", "b:2"}},
+	}
+	if !reflect.DeepEqual(overrides, want) {
+		t.Errorf("overrides = %+v, want %+v", overrides, want)
+	}
+	if !reflect.DeepEqual(unknown, []string{"APP_TYPO"}) {
+		t.Errorf("unknown = %v, want [APP_TYPO]", unknown)
+	}
+	if overrides, unknown := envOverrides("", []string{"APP_HTTP_PORT=1"}); overrides != nil || unknown != nil {
+		t.Errorf("empty prefix should disable overrides, got %v %v", overrides, unknown)
+	}
+}
+
+// 平滑升级传给子进程的环境变量以及k8s为Service注入的环境变量不能导致加载失败
+func TestLoadConfigSkipsUnknownEnv(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.toml")
+	if err := os.WriteFile(file, []byte("http_port = 8000\n[dbs.default]\nuri = \"root:pw@tcp(db:3306)/app\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FOUNDATION_LISTEN_FDS", "0.0.0.0:8000")
+	t.Setenv("FOUNDATION_READY_FD", "4")
+	t.Setenv("FOUNDATION_SERVICE_HOST", "10.0.0.1")
+	t.Setenv("FOUNDATION_PORT_8000_TCP", "tcp://10.0.0.1:8000")
+	t.Setenv("FOUNDATION_HTTP_PORT", "9000")
+	t.Setenv("FOUNDATION_DBS_DEFAULT_MAX_OPEN_CONN", "7")
+
+	config, err := LoadConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.HttpPort != 9000 || config.DbsConfig["default"].MaxOpenConn != 7 {
+		t.Errorf("overrides not applied: http_port=%d max_open_conn=%d", config.HttpPort, config.DbsConfig["default"].MaxOpenConn)
+	}
+	if config.DbsConfig["default"].Uri != "root:pw@tcp(db:3306)/app" {
+		t.Errorf("file value lost: %q", config.DbsConfig["default"].Uri)
+	}
+	want := []string{"FOUNDATION_LISTEN_FDS", "FOUNDATION_PORT_8000_TCP", "FOUNDATION_READY_FD", "FOUNDATION_SERVICE_HOST"}
+	if got := config.UnknownEnv(); !reflect.DeepEqual(got, want) {
+		t.Errorf("UnknownEnv() = %v, want %v", got, want)
+	}
+	if source := config.Source("http_port"); source != "env:FOUNDATION_HTTP_PORT" {
+		t.Errorf("Source(http_port) = %q", source)
+	}
+}
@@ -0,0 +1,169 @@
+package cfg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+var (
+	current atomic.Pointer[AppConfig]
+
+	reloadMu      sync.Mutex // 保证同一时间只有一次重新加载，且订阅者按配置替换的顺序收到通知
+	subscribersMu sync.Mutex
+	subscribers   []*subscriber
+)
+
+type subscriber struct {
+	changed func(old, new *AppConfig) bool
+	apply   func(config *AppConfig)
+}
+
+// Current 获取当前生效的配置，配置重新加载后返回新的配置。
+// AppConf及App.Config()始终为启动时加载的配置，需要感知配置变化的地方应使用Current或Subscribe
+func Current() *AppConfig {
+	if config := current.Load(); config != nil {
+		return config
+	}
+	return AppConf
+}
+
+// SetCurrent 设置当前生效的配置，并设置为全局配置AppConf
+func SetCurrent(config *AppConfig) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	AppConf = config
+	current.Store(config)
+}
+
+// Subscribe 订阅配置段的变化，section从配置中取出关心的部分，重新加载后取值发生变化(reflect.DeepEqual)时以新的取值调用fn。
+// fn不能返回错误，需要校验的配置项应在Validate中校验，保证配置不会只生效一部分。返回取消订阅的方法
+func Subscribe[T any](section func(config *AppConfig) T, fn func(value T)) (cancel func()) {
+	sub := &subscriber{
+		changed: func(old, new *AppConfig) bool {
+			return !reflect.DeepEqual(section(old), section(new))
+		},
+		apply: func(config *AppConfig) {
+			fn(section(config))
+		},
+	}
+	subscribersMu.Lock()
+	subscribers = append(subscribers, sub)
+	subscribersMu.Unlock()
+
+	return func() {
+		subscribersMu.Lock()
+		defer subscribersMu.Unlock()
+		for i, existed := range subscribers {
+			if existed == sub {
+				subscribers = append(subscribers[:i:i], subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Reload 重新加载当前配置对应的配置文件与环境变量，校验通过后替换当前配置并通知订阅者，
+// 加载或校验失败时返回错误，当前配置保持不变
+func Reload() (*AppConfig, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	old := current.Load()
+	if old == nil || old.File == "" {
+		return nil, errors.New("config is not loaded from file")
+	}
+	next := newConfig(old.File)
+	if err := next.load(); err != nil {
+		return nil, err
+	}
+	next.ApplyDefaults()
+	if err := next.Validate(); err != nil {
+		return nil, err
+	}
+
+	current.Store(next)
+
+	subscribersMu.Lock()
+	subs := make([]*subscriber, len(subscribers))
+	copy(subs, subscribers)
+	subscribersMu.Unlock()
+	for _, sub := range subs {
+		if sub.changed(old, next) {
+			sub.apply(next)
+		}
+	}
+	return next, nil
+}
+
+// Watch 监听当前配置所在目录，配置文件变化后重新加载，直到ctx取消。
+// 每次重新加载后以新配置或错误调用onReload，失败时保持原配置不变
+func Watch(ctx context.Context, onReload func(config *AppConfig, err error)) error {
+	config := current.Load()
+	if config == nil || config.File == "" {
+		return errors.New("config is not loaded from file")
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	// 监听目录而不是文件，编辑器保存或k8s更新ConfigMap时会替换文件
+	dir := filepath.Dir(config.File)
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("watch config dir %s failed: %w", dir, err)
+	}
+
+	// 一次保存可能触发多个事件，合并后只加载一次
+	const debounce = 200 * time.Millisecond
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return errors.New("config watcher closed")
+			}
+			if isConfigFile(config.File, event.Name) && event.Op != fsnotify.Chmod {
+				timer.Reset(debounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return errors.New("config watcher closed")
+			}
+			onReload(nil, fmt.Errorf("watch config failed: %w", err))
+		case <-timer.C:
+			onReload(Reload())
+		}
+	}
+}
+
+// 是否为基础配置及对应的环境配置、本地配置，k8s挂载的ConfigMap更新时替换..data目录
+func isConfigFile(base, name string) bool {
+	baseName := filepath.Base(name)
+	if baseName == "..data" {
+		return true
+	}
+	stem := strings.TrimSuffix(filepath.Base(base), filepath.Ext(base))
+	if !strings.HasPrefix(baseName, stem+".") {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(baseName))
+	for _, candidate := range configExts {
+		if ext == candidate {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package cfg
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// MasterKeyEnv 保存主密钥的环境变量，值为base64编码的16、24或32字节密钥，用于解密enc:开头的配置值，
+// 可通过 head -c 32 /dev/urandom | base64 生成
+const MasterKeyEnv = "CONFIG_MASTER_KEY"
+
+// EncPrefix 加密配置值的前缀，其后为base64编码的nonce与AES-GCM密文
+const EncPrefix = "enc:"
+
+// 配置值中的密钥引用：${env:NAME}读取环境变量，${file:/run/secrets/db}读取文件内容(去掉末尾换行)
+var secretRefPattern = regexp.MustCompile(`\$\{(env|file):([^}]+)\}`)
+
+// MasterKey 从环境变量读取主密钥
+func MasterKey() ([]byte, error) {
+	encoded := os.Getenv(MasterKeyEnv)
+	if encoded == "" {
+		return nil, fmt.Errorf("%s is not set", MasterKeyEnv)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not valid base64", MasterKeyEnv)
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("%s must be 16, 24 or 32 bytes, got %d", MasterKeyEnv, len(key))
+	}
+}
+
+// Encrypt 使用AES-GCM加密配置值，返回enc:开头的密文，可直接写入配置文件
+func Encrypt(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return EncPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密enc:开头的配置值，错误信息中不包含密文与明文
+func Decrypt(key []byte, value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, EncPrefix))
+	if err != nil {
+		return "", errors.New("encrypted value is not valid base64")
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted value is too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt failed, check %s", MasterKeyEnv)
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// 解析所有配置项中的密钥引用与加密值，替换为实际值，并记录包含密钥的配置项，导出配置时整体隐藏
+func (cfg *AppConfig) resolveSecrets() error {
+	cfg.secrets = make(map[string]struct{})
+	r := &secretResolver{}
+	var errs ValidationErrors
+	for _, key := range cfg.viper.AllKeys() {
+		var resolved interface{}
+		var secret bool
+		var err error
+		switch value := cfg.viper.Get(key).(type) {
+		case string:
+			resolved, secret, err = r.resolve(value)
+		case []interface{}:
+			items := make([]interface{}, len(value))
+			for i, item := range value {
+				items[i] = item
+				if s, ok := item.(string); ok {
+					var itemSecret bool
+					if items[i], itemSecret, err = r.resolve(s); err != nil {
+						break
+					}
+					secret = secret || itemSecret
+				}
+			}
+			resolved = items
+		case []string:
+			items := make([]string, len(value))
+			for i, item := range value {
+				var itemSecret bool
+				if items[i], itemSecret, err = r.resolve(item); err != nil {
+					break
+				}
+				secret = secret || itemSecret
+			}
+			resolved = items
+		default:
+			continue
+		}
+		if err != nil {
+			errs = append(errs, &ValidationError{Key: key, Msg: err.Error()})
+			continue
+		}
+		if secret {
+			if err := cfg.set(key, resolved); err != nil {
+				return err
+			}
+			cfg.secrets[key] = struct{}{}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// IsSecret 配置项是否由密钥引用或加密值解析得到，key为"dbs.default.uri"形式的路径
+func (cfg *AppConfig) IsSecret(key string) bool {
+	_, ok := cfg.secrets[strings.ToLower(key)]
+	return ok
+}
+
+type secretResolver struct {
+	key    []byte
+	keyErr error
+	loaded bool
+}
+
+// 主密钥只在存在加密值时读取
+func (r *secretResolver) masterKey() ([]byte, error) {
+	if !r.loaded {
+		r.key, r.keyErr = MasterKey()
+		r.loaded = true
+	}
+	return r.key, r.keyErr
+}
+
+func (r *secretResolver) resolve(value string) (string, bool, error) {
+	if strings.HasPrefix(value, EncPrefix) {
+		key, err := r.masterKey()
+		if err != nil {
+			return "", false, err
+		}
+		plaintext, err := Decrypt(key, value)
+		return plaintext, err == nil, err
+	}
+	if !strings.Contains(value, "${") {
+		return value, false, nil
+	}
+
+	var err error
+	resolved := secretRefPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		match := secretRefPattern.FindStringSubmatch(ref)
+		kind, name := match[1], strings.TrimSpace(match[2])
+		switch kind {
+		case "env":
+			env, ok := os.LookupEnv(name)
+			if !ok && err == nil {
+				err = fmt.Errorf("environment variable %s referenced by ${env:%s} is not set", name, name)
+			}
+			return env
+		default:
+			content, readErr := os.ReadFile(name)
+			if readErr != nil && err == nil {
+				err = fmt.Errorf("read secret file failed: %v", readErr)
+			}
+			return strings.TrimRight(string(content), "\r\n")
+		}
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return resolved, resolved != value, nil
+}
@@ -0,0 +1,96 @@
+package cfg
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	encrypted, err := Encrypt(key, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encrypted, EncPrefix) {
+		t.Fatalf("Encrypt() = %q, want %s prefix", encrypted, EncPrefix)
+	}
+	if got, err := Decrypt(key, encrypted); err != nil || got != "s3cret" {
+		t.Errorf("Decrypt() = %q, %v", got, err)
+	}
+}
+
+func TestDecryptWithWrongKey(t *testing.T) {
+	encrypted, err := Encrypt([]byte("0123456789abcdef"), "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Decrypt([]byte("fedcba9876543210"), encrypted)
+	if err == nil || strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("Decrypt() error = %v, want error without plaintext", err)
+	}
+}
+
+func TestLoadConfigMapResolvesSecrets(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	t.Setenv(MasterKeyEnv, base64.StdEncoding.EncodeToString(key))
+	t.Setenv("TEST_REDIS_PASS", "redis-pass")
+	encrypted, err := Encrypt(key, "root:db-pass@tcp(db:3306)/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "sign")
+	if err = os.WriteFile(file, []byte("sign-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfigMap(map[string]interface{}{
+		"redis.pass":      "${env:TEST_REDIS_PASS}",
+		"sign.secret":     "${file:" + file + "}",
+		"dbs.default.uri": encrypted,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.RedisConfig.Pass != "redis-pass" {
+		t.Errorf("redis.pass = %q", config.RedisConfig.Pass)
+	}
+	if config.SignConfig.Secret != "sign-secret" {
+		t.Errorf("sign.secret = %q", config.SignConfig.Secret)
+	}
+	if config.DbsConfig["default"].Uri != "root:db-pass@tcp(db:3306)/app" {
+		t.Errorf("dbs.default.uri = %q", config.DbsConfig["default"].Uri)
+	}
+	// 由密钥解析得到的连接串整体隐藏
+	if !config.IsSecret("dbs.default.uri") || lookup(config.Redacted(), "dbs.default.uri") != Redacted {
+		t.Errorf("dbs.default.uri is not redacted: %v", lookup(config.Redacted(), "dbs.default.uri"))
+	}
+}
+
+func TestResolveSecretsAggregatesErrors(t *testing.T) {
+	t.Setenv(MasterKeyEnv, "")
+	_, err := LoadConfigMap(map[string]interface{}{
+		"redis.pass":  "${env:TEST_MISSING_ENV}",
+		"sign.secret": "${file:/nonexistent/secret}",
+		"mongo.uri":   EncPrefix + "AAAA",
+	})
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Errorf("LoadConfigMap() error = %v, want 3 validation errors", err)
+	}
+}
+
+func TestValidateRedactsSecrets(t *testing.T) {
+	t.Setenv("TEST_REDIS_ADDR", "sekrit-host")
+	config, err := LoadConfigMap(map[string]interface{}{"redis.addr": "${env:TEST_REDIS_ADDR}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = config.Validate()
+	if err == nil || strings.Contains(err.Error(), "sekrit") {
+		t.Errorf("Validate() error = %v, want redacted redis.addr", err)
+	}
+}
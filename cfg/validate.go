@@ -0,0 +1,378 @@
+package cfg
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.uber.org/zap/zapcore"
+)
+
+// ValidationError 单个配置项的校验错误
+type ValidationError struct {
+	Key string // 配置项路径，如dbs.default.uri
+	Msg string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Key + ": " + e.Msg
+}
+
+// ValidationErrors 配置校验发现的所有错误，按配置项路径排序
+type ValidationErrors []*ValidationError
+
+func (errs ValidationErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = err.Error()
+	}
+	return fmt.Sprintf("%d invalid config items:\n  %s", len(errs), strings.Join(lines, "\n  "))
+}
+
+type validator struct {
+	errs    ValidationErrors
+	secrets map[string]struct{}
+}
+
+// 由密钥解析得到的配置项，错误信息中的字符串参数替换为******
+func (v *validator) add(key, format string, args ...interface{}) {
+	if v.isSecret(key) {
+		for i, arg := range args {
+			if _, ok := arg.(string); ok {
+				args[i] = Redacted
+			}
+		}
+	}
+	v.errs = append(v.errs, &ValidationError{Key: key, Msg: fmt.Sprintf(format, args...)})
+}
+
+// 数组元素的路径如redis_cluster.addrs[0]，按数组整体判断
+func (v *validator) isSecret(key string) bool {
+	if i := strings.Index(key, "["); i >= 0 {
+		key = key[:i]
+	}
+	_, ok := v.secrets[key]
+	return ok
+}
+
+// Validate 校验配置中的端口、连接串、时长、地址以及配置项之间的约束，一次返回所有错误，类型为ValidationErrors
+func (cfg *AppConfig) Validate() error {
+	v := &validator{secrets: cfg.secrets}
+	cfg.validateServer(v)
+	cfg.validateLog(v)
+	cfg.validateTls(v)
+	cfg.validateDbs(v)
+	cfg.validateMongo(v)
+	cfg.validateRedis(v)
+	cfg.validateKafka(v)
+	cfg.validateSecurity(v)
+	cfg.validateLimiter(v)
+	if len(v.errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		return v.errs[i].Key < v.errs[j].Key
+	})
+	return v.errs
+}
+
+func (cfg *AppConfig) validateServer(v *validator) {
+	v.host("http_addr", cfg.HttpAddr)
+	v.host("grpc_addr", cfg.GrpcAddr)
+	v.host("admin_addr", cfg.AdminAddr)
+	if cfg.HttpPort <= 0 || cfg.HttpPort > 65535 {
+		v.add("http_port", "must be between 1 and 65535, got %d", cfg.HttpPort)
+	}
+	v.port("grpc_port", cfg.GrpcPort)
+	v.port("admin_port", cfg.AdminPort)
+	if cfg.AdminEnabled() && cfg.AdminPort == cfg.HttpPort {
+		v.add("admin_port", "must differ from http_port %d", cfg.HttpPort)
+	}
+	if cfg.GrpcStandalone() && cfg.AdminEnabled() && cfg.GrpcPort == cfg.AdminPort {
+		v.add("grpc_port", "must differ from admin_port %d", cfg.AdminPort)
+	}
+	v.nonNegative("http_timeout", cfg.HttpTimeout)
+
+	if conf := cfg.ServerConfig; conf != nil {
+		v.nonNegative("server.read_header_timeout", conf.ReadHeaderTimeout)
+		v.nonNegative("server.read_timeout", conf.ReadTimeout)
+		v.nonNegative("server.write_timeout", conf.WriteTimeout)
+		v.nonNegative("server.idle_timeout", conf.IdleTimeout)
+		v.nonNegative("server.max_header_bytes", conf.MaxHeaderBytes)
+	}
+	if conf := cfg.ShutdownConfig; conf != nil {
+		v.nonNegative("shutdown.wait_before_drain", conf.WaitBeforeDrain)
+		v.nonNegative("shutdown.http_timeout", conf.HttpTimeout)
+		v.nonNegative("shutdown.worker_timeout", conf.WorkerTimeout)
+		v.nonNegative("shutdown.client_timeout", conf.ClientTimeout)
+		v.nonNegative("shutdown.hook_timeout", conf.HookTimeout)
+	}
+}
+
+func (cfg *AppConfig) validateLog(v *validator) {
+	if cfg.LogLevel != "" {
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+			v.add("log_level", "invalid level %q, use debug, info, warn, error, dpanic, panic or fatal", cfg.LogLevel)
+		}
+	}
+	switch cfg.LogMode {
+	case "console":
+	case "file":
+		if cfg.LogFile == "" {
+			v.add("log_file", "is required when log_mode is file")
+		}
+		if cfg.AccessLogFile == "" {
+			v.add("access_log_file", "is required when log_mode is file")
+		}
+	default:
+		v.add("log_mode", "invalid mode %q, use console or file", cfg.LogMode)
+	}
+}
+
+func (cfg *AppConfig) validateTls(v *validator) {
+	if (cfg.TlsCertFile == "") != (cfg.TlsKeyFile == "") {
+		v.add("tls_cert_file", "tls_cert_file and tls_key_file must be set together")
+	}
+	v.file("tls_cert_file", cfg.TlsCertFile)
+	v.file("tls_key_file", cfg.TlsKeyFile)
+	v.file("tls_client_ca_file", cfg.TlsClientCaFile)
+	if cfg.TlsClientCaFile != "" && !cfg.TlsEnabled() {
+		v.add("tls_client_ca_file", "requires tls_cert_file and tls_key_file")
+	}
+}
+
+func (cfg *AppConfig) validateDbs(v *validator) {
+	for _, name := range sortedKeys(cfg.DbsConfig) {
+		conf, key := cfg.DbsConfig[name], "dbs."+name
+		if conf == nil {
+			v.add(key, "is empty")
+			continue
+		}
+		if conf.Uri == "" {
+			v.add(key+".uri", "is required")
+		} else if _, err := mysql.ParseDSN(conf.Uri); err != nil {
+			// 错误信息中可能包含连接串，只返回隐藏密码后的连接串
+			v.add(key+".uri", "invalid mysql dsn %q", RedactUri(conf.Uri))
+		}
+		v.nonNegative(key+".max_idle_conn", conf.MaxIdleConn)
+		v.nonNegative(key+".max_open_conn", conf.MaxOpenConn)
+		v.nonNegative(key+".connect_idle_time", conf.ConnectIdleTime)
+		v.nonNegative(key+".connect_life_time", conf.ConnectLifeTime)
+		v.nonNegative(key+".connect_timeout", conf.ConnectTimeout)
+		if conf.MaxOpenConn > 0 && conf.MaxIdleConn > conf.MaxOpenConn {
+			v.add(key+".max_idle_conn", "must not exceed max_open_conn %d", conf.MaxOpenConn)
+		}
+	}
+}
+
+func (cfg *AppConfig) validateMongo(v *validator) {
+	conf := cfg.MongoConfig
+	if conf == nil {
+		return
+	}
+	if conf.Uri == "" {
+		v.add("mongo.uri", "is required")
+	} else if u, err := url.Parse(conf.Uri); err != nil || (u.Scheme != "mongodb" && u.Scheme != "mongodb+srv") || u.Host == "" {
+		v.add("mongo.uri", "invalid uri %q, expect mongodb://host:port or mongodb+srv://host", RedactUri(conf.Uri))
+	}
+	if conf.Db == "" {
+		v.add("mongo.db", "is required")
+	}
+	if conf.MaxPoolSize > 0 && conf.MinPoolSize > conf.MaxPoolSize {
+		v.add("mongo.min_pool_size", "must not exceed max_pool_size %d", conf.MaxPoolSize)
+	}
+}
+
+func (cfg *AppConfig) validateRedis(v *validator) {
+	if conf := cfg.RedisConfig; conf != nil {
+		v.hostPort("redis.addr", conf.Addr)
+		v.nonNegative("redis.db", conf.Db)
+		v.pool("redis", conf.MinIdle, conf.PoolSize)
+		v.nonNegative("redis.connect_timeout", conf.ConnectTimeout)
+		v.nonNegative("redis.read_timeout", conf.ReadTimeout)
+		v.nonNegative("redis.write_timeout", conf.WriteTimeout)
+	}
+	if conf := cfg.RedisClusterConfig; conf != nil {
+		if len(conf.Addrs) == 0 {
+			v.add("redis_cluster.addrs", "is required")
+		}
+		for i, addr := range conf.Addrs {
+			v.hostPort("redis_cluster.addrs["+strconv.Itoa(i)+"]", addr)
+		}
+		v.pool("redis_cluster", conf.MinIdle, conf.PoolSize)
+		v.nonNegative("redis_cluster.connect_timeout", conf.ConnectTimeout)
+		v.nonNegative("redis_cluster.read_timeout", conf.ReadTimeout)
+		v.nonNegative("redis_cluster.write_timeout", conf.WriteTimeout)
+	}
+}
+
+func (cfg *AppConfig) validateKafka(v *validator) {
+	conf := cfg.KafkaConfig
+	if conf == nil {
+		return
+	}
+	for _, name := range sortedKeys(conf.Producers) {
+		producer, key := conf.Producers[name], "kafka.producers."+name
+		if producer == nil {
+			v.add(key, "is empty")
+			continue
+		}
+		v.hostPort(key+".broker", producer.Broker)
+		if producer.Topic == "" {
+			v.add(key+".topic", "is required")
+		}
+	}
+	for _, name := range sortedKeys(conf.Consumers) {
+		consumer, key := conf.Consumers[name], "kafka.consumers."+name
+		if consumer == nil {
+			v.add(key, "is empty")
+			continue
+		}
+		v.hostPort(key+".broker", consumer.Broker)
+		if consumer.Topic == "" {
+			v.add(key+".topic", "is required")
+		}
+		v.nonNegative(key+".partition", consumer.Partition)
+		// kafka-go的消费组与指定分区不能同时使用
+		if consumer.Group != "" && consumer.Partition != 0 {
+			v.add(key+".partition", "must not be set together with group %q", consumer.Group)
+		}
+	}
+}
+
+func (cfg *AppConfig) validateSecurity(v *validator) {
+	if conf := cfg.SignConfig; conf != nil && conf.Secret == "" && conf.Salt != "" {
+		v.add("sign.secret", "is required when sign.salt is set")
+	}
+	if conf := cfg.TsConfig; conf != nil && conf.Expire != "" {
+		if expire, err := time.ParseDuration(conf.Expire); err != nil || expire <= 0 {
+			v.add("ts.expire", "invalid duration %q, expect a positive duration such as 30s or 5m", conf.Expire)
+		}
+	}
+	if conf := cfg.AdminConfig; conf != nil {
+		if (conf.User == "") != (conf.Pass == "") {
+			v.add("admin.user", "admin.user and admin.pass must be set together")
+		}
+		for i, allow := range conf.AllowIps {
+			if net.ParseIP(allow) == nil {
+				if _, _, err := net.ParseCIDR(allow); err != nil {
+					v.add("admin.allow_ips["+strconv.Itoa(i)+"]", "invalid ip or cidr %q", allow)
+				}
+			}
+		}
+	}
+}
+
+func (cfg *AppConfig) validateLimiter(v *validator) {
+	conf := cfg.LimiterConfig
+	if conf == nil {
+		return
+	}
+	v.nonNegative("limiter.max_inflight", conf.MaxInflight)
+	v.nonNegative("limiter.min_limit", conf.MinLimit)
+	v.nonNegative("limiter.latency_threshold", conf.LatencyThreshold)
+	v.nonNegative("limiter.retry_after", conf.RetryAfter)
+	switch conf.Adaptive {
+	case "", "aimd", "gradient":
+	default:
+		v.add("limiter.adaptive", "invalid algorithm %q, use aimd or gradient", conf.Adaptive)
+	}
+	if conf.Adaptive != "" && conf.MaxInflight > 0 && conf.MinLimit > conf.MaxInflight {
+		v.add("limiter.min_limit", "must not exceed max_inflight %d", conf.MaxInflight)
+	}
+	for _, route := range sortedKeys(conf.RouteMaxInflight) {
+		if conf.RouteMaxInflight[route] <= 0 {
+			v.add("limiter.route_max_inflight."+route, "must be positive, got %d", conf.RouteMaxInflight[route])
+		}
+	}
+}
+
+// 为空表示监听所有地址
+func (v *validator) host(key, host string) {
+	if host != "" && net.ParseIP(host) == nil && !isHostname(host) {
+		v.add(key, "invalid host %q", host)
+	}
+}
+
+func (v *validator) hostPort(key, addr string) {
+	if addr == "" {
+		v.add(key, "is required")
+		return
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		v.add(key, "invalid address %q, expect host:port", addr)
+		return
+	}
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		v.add(key, "invalid port in %q", addr)
+	}
+	if host != "" && net.ParseIP(host) == nil && !isHostname(host) {
+		v.add(key, "invalid host in %q", addr)
+	}
+}
+
+// 为0表示不启用
+func (v *validator) port(key string, port int) {
+	if port < 0 || port > 65535 {
+		v.add(key, "must be between 0 and 65535, got %d", port)
+	}
+}
+
+func (v *validator) nonNegative(key string, value int) {
+	if value < 0 {
+		v.add(key, "must not be negative, got %d", value)
+	}
+}
+
+func (v *validator) pool(key string, minIdle, poolSize int) {
+	v.nonNegative(key+".min_idle", minIdle)
+	v.nonNegative(key+".pool_size", poolSize)
+	if poolSize > 0 && minIdle > poolSize {
+		v.add(key+".min_idle", "must not exceed pool_size %d", poolSize)
+	}
+}
+
+func (v *validator) file(key, file string) {
+	if file == "" {
+		return
+	}
+	if info, err := os.Stat(file); err != nil {
+		v.add(key, "file %s is not accessible: %v", file, err)
+	} else if info.IsDir() {
+		v.add(key, "%s is a directory", file)
+	}
+}
+
+func isHostname(host string) bool {
+	if len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,206 @@
+package foundation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/easonchen147/foundation/buildinfo"
+	"github.com/easonchen147/foundation/cfg"
+
+	"github.com/spf13/cobra"
+)
+
+// Command 自定义子命令，执行前已按配置完成组件初始化，执行后释放组件
+type Command struct {
+	Use   string // 命令名称及参数说明，如"import <file>"
+	Short string // 命令说明
+	Run   func(ctx context.Context, app *App, args []string) error
+}
+
+// Main 命令行入口，提供serve、config print、config validate、version、migrate以及自定义子命令，
+// 未指定子命令时等同于serve，执行失败时以状态码1退出
+func Main(opts ...Option) {
+	if err := NewCommand(opts...).ExecuteContext(context.Background()); err != nil {
+		os.Exit(1)
+	}
+}
+
+// NewCommand 创建命令行的根命令，便于嵌入已有的cobra命令
+func NewCommand(opts ...Option) *cobra.Command {
+	var configFile string
+	// 命令行指定的配置文件优先于环境变量CONFIG_FILE
+	buildOptions := func() *options {
+		o := &options{}
+		for _, opt := range opts {
+			opt(o)
+		}
+		if configFile != "" {
+			o.configFile = configFile
+		}
+		return o
+	}
+	serve := func(cmd *cobra.Command, args []string) error {
+		app, err := newApp(buildOptions())
+		if err != nil {
+			return err
+		}
+		return app.Run(cmd.Context())
+	}
+	root := &cobra.Command{
+		Use:               filepath.Base(os.Args[0]),
+		SilenceUsage:      true,
+		RunE:              serve,
+		Args:              cobra.NoArgs,
+		CompletionOptions: cobra.CompletionOptions{DisableDefaultCmd: true},
+	}
+	root.PersistentFlags().StringVarP(&configFile, "config", "c", "", "base config file, default $CONFIG_FILE or app.toml")
+
+	root.AddCommand(&cobra.Command{
+		Use:   "serve",
+		Short: "Start the http server and background workers",
+		Args:  cobra.NoArgs,
+		RunE:  serve,
+	})
+
+	configCmd := &cobra.Command{Use: "config", Short: "Inspect the effective config"}
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "print",
+		Short: "Print the effective config with secrets redacted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := loadConfig(buildOptions())
+			if err != nil {
+				return err
+			}
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(config.Redacted())
+		},
+	}, &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the config file and report every invalid item",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := loadConfig(buildOptions())
+			if err != nil {
+				return err
+			}
+			if err := config.Validate(); err != nil {
+				return fmt.Errorf("config %s is invalid, %w", strings.Join(config.Layers(), ","), err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "config %s is valid\n", config.File)
+			return nil
+		},
+	}, &cobra.Command{
+		Use:   "sources",
+		Short: "Print the loaded config files and the source file of each key",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := loadConfig(buildOptions())
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "# files: %s\n", strings.Join(config.Layers(), " < "))
+			sources := config.Sources()
+			keys := make([]string, 0, len(sources))
+			for key := range sources {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Fprintf(out, "%s = %s\n", key, sources[key])
+			}
+			return nil
+		},
+	})
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "encrypt [value]",
+		Short: "Encrypt a config value with the master key in $" + cfg.MasterKeyEnv + ", read from stdin when value is omitted",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := cfg.MasterKey()
+			if err != nil {
+				return fmt.Errorf("%w, generate one with: head -c 32 /dev/urandom | base64", err)
+			}
+			var value string
+			if len(args) > 0 {
+				value = args[0]
+			} else {
+				// 从标准输入读取可以避免明文留在shell历史中
+				data, err := io.ReadAll(cmd.InOrStdin())
+				if err != nil {
+					return err
+				}
+				value = strings.TrimRight(string(data), "\r\n")
+			}
+			encrypted, err := cfg.Encrypt(key, value)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), encrypted)
+			return nil
+		},
+	})
+	root.AddCommand(configCmd)
+
+	root.AddCommand(&cobra.Command{
+		Use:   "version",
+		Short: "Print the version",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			info := buildinfo.Get()
+			fmt.Fprintf(cmd.OutOrStdout(), "version:    %s\ncommit:     %s\nbuild time: %s\nmodified:   %t\ngo:         %s\n",
+				info.Version, info.Commit, info.BuildTime, info.Modified, info.GoVersion)
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "migrate",
+		Short: "Run the registered migration",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o := buildOptions()
+			if o.migration == nil {
+				return errors.New("no migration registered, use foundation.WithMigration")
+			}
+			return runWithApp(cmd.Context(), o, func(ctx context.Context, app *App) error {
+				return o.migration(ctx, app)
+			})
+		},
+	})
+
+	for _, command := range buildOptions().commands {
+		command := command
+		root.AddCommand(&cobra.Command{
+			Use:   command.Use,
+			Short: command.Short,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runWithApp(cmd.Context(), buildOptions(), func(ctx context.Context, app *App) error {
+					return command.Run(ctx, app, args)
+				})
+			},
+		})
+	}
+	return root
+}
+
+// 初始化应用后执行一次性任务，不启动http服务，收到退出信号时取消ctx
+func runWithApp(ctx context.Context, o *options, fn func(ctx context.Context, app *App) error) error {
+	app, err := newApp(o)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	return errors.Join(fn(ctx, app), app.Stop(context.Background()))
+}
@@ -8,4 +8,8 @@ const (
 const (
 	NanoIdAlphbet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 	TraceIdKey    = "traceId"
+
+	TraceIdMetadataKey = "x-trace-id"
+
+	ClientIdentityKey = "clientIdentity"
 )
@@ -0,0 +1,171 @@
+// Package container 按类型与名称注册组件的依赖容器，组件在首次获取时才创建，创建失败时返回错误。
+// 基础组件初始化时注册到Default，db.DB、cache.Redis等全局方法均从Default获取。
+package container
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Default 应用默认的容器
+var Default = New()
+
+// Container 依赖容器
+type Container struct {
+	mu      sync.RWMutex
+	entries map[key]*entry
+}
+
+type key struct {
+	typ  reflect.Type
+	name string
+}
+
+type entry struct {
+	mu       sync.Mutex
+	provider func() (interface{}, error)
+	value    interface{}
+	resolved bool
+	ready    atomic.Bool // 与resolved一致，Resolved读取时不需要等待正在进行的创建
+	override bool        // 通过Override设置的值不会被Provide替换
+}
+
+// New 创建容器
+func New() *Container {
+	return &Container{entries: make(map[key]*entry)}
+}
+
+func keyOf[T any](name string) key {
+	return key{typ: reflect.TypeOf((*T)(nil)).Elem(), name: name}
+}
+
+// Provide 注册组件的创建方法，同类型同名称的组件会被替换，已被Override的组件保持不变。
+// provider中不能获取组件自身，否则会死锁
+func Provide[T any](c *Container, name string, provider func() (T, error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := keyOf[T](name)
+	if e, ok := c.entries[k]; ok && e.override {
+		return
+	}
+	c.entries[k] = &entry{provider: func() (interface{}, error) {
+		return provider()
+	}}
+}
+
+// Override 直接设置组件，优先于Provide注册的创建方法，用于在测试中替换组件
+func Override[T any](c *Container, name string, value T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := &entry{value: value, resolved: true, override: true}
+	e.ready.Store(true)
+	c.entries[keyOf[T](name)] = e
+}
+
+// Resolve 获取组件，首次获取时调用创建方法，创建失败时下次获取会重试
+func Resolve[T any](c *Container, name string) (T, error) {
+	var zero T
+	k := keyOf[T](name)
+	c.mu.RLock()
+	e, ok := c.entries[k]
+	c.mu.RUnlock()
+	if !ok {
+		return zero, fmt.Errorf("%s %q is not provided", k.typ, name)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.resolved {
+		value, err := e.provider()
+		if err != nil {
+			return zero, fmt.Errorf("resolve %s %q failed: %w", k.typ, name, err)
+		}
+		e.value, e.resolved = value, true
+		e.ready.Store(true)
+	}
+	return e.value.(T), nil
+}
+
+// MustResolve 获取组件，失败时panic
+func MustResolve[T any](c *Container, name string) T {
+	value, err := Resolve[T](c, name)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// Resolved 获取已经创建的组件，不会触发创建，也不会等待正在进行的创建，用于关闭资源、采集指标、健康检查等场景
+func Resolved[T any](c *Container, name string) (T, bool) {
+	var zero T
+	c.mu.RLock()
+	e, ok := c.entries[keyOf[T](name)]
+	c.mu.RUnlock()
+	if !ok || !e.ready.Load() {
+		return zero, false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.resolved {
+		return zero, false
+	}
+	return e.value.(T), true
+}
+
+// Has 是否已注册组件
+func Has[T any](c *Container, name string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.entries[keyOf[T](name)]
+	return ok
+}
+
+// Names 获取某类型已注册组件的名称，按名称排序
+func Names[T any](c *Container) []string {
+	typ := keyOf[T]("").typ
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var names []string
+	for k := range c.entries {
+		if k.typ == typ {
+			names = append(names, k.name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Reset 移除所有组件
+func (c *Container) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[key]*entry)
+}
+
+// Entry 已注册组件的状态
+type Entry struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Resolved bool   `json:"resolved"` // 是否已创建
+	Override bool   `json:"override"` // 是否通过Override设置
+}
+
+// Entries 获取所有已注册组件的状态，按类型、名称排序，正在创建中的组件Resolved为false
+func (c *Container) Entries() []Entry {
+	c.mu.RLock()
+	entries := make([]Entry, 0, len(c.entries))
+	for k, e := range c.entries {
+		entries = append(entries, Entry{Type: k.typ.String(), Name: k.name, Resolved: e.ready.Load(), Override: e.override})
+	}
+	c.mu.RUnlock()
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Type != entries[j].Type {
+			return entries[i].Type < entries[j].Type
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
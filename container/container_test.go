@@ -0,0 +1,69 @@
+package container
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestResolveRetriesAfterFailure(t *testing.T) {
+	c := New()
+	calls := 0
+	Provide(c, "", func() (string, error) {
+		calls++
+		if calls == 1 {
+			return "", errors.New("unavailable")
+		}
+		return "ok", nil
+	})
+	if _, err := Resolve[string](c, ""); err == nil {
+		t.Fatal("first Resolve() error = nil")
+	}
+	if got, err := Resolve[string](c, ""); err != nil || got != "ok" {
+		t.Errorf("Resolve() = %q, %v, want ok", got, err)
+	}
+}
+
+func TestOverrideWinsOverProvide(t *testing.T) {
+	c := New()
+	Override(c, "", "override")
+	Provide(c, "", func() (string, error) { return "provided", nil })
+	if got := MustResolve[string](c, ""); got != "override" {
+		t.Errorf("MustResolve() = %q, want override", got)
+	}
+}
+
+func TestResolvedDoesNotWaitForCreation(t *testing.T) {
+	c := New()
+	entered, release := make(chan struct{}), make(chan struct{})
+	Provide(c, "", func() (int, error) {
+		close(entered)
+		<-release
+		return 1, nil
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = Resolve[int](c, "")
+	}()
+	<-entered
+
+	result := make(chan bool, 1)
+	go func() {
+		_, ok := Resolved[int](c, "")
+		result <- ok
+	}()
+	select {
+	case ok := <-result:
+		if ok {
+			t.Error("Resolved() = true while creating")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Resolved() blocked while creating")
+	}
+	close(release)
+	<-done
+	if v, ok := Resolved[int](c, ""); !ok || v != 1 {
+		t.Errorf("Resolved() = %v, %v, want 1, true", v, ok)
+	}
+}
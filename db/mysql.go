@@ -1,69 +1,88 @@
 package db
 
 import (
-	"errors"
+	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
 	"github.com/easonchen147/foundation/cfg"
+	"github.com/easonchen147/foundation/container"
 	"github.com/easonchen147/foundation/log"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
 	"moul.io/zapgorm2"
 )
 
-var (
-	db    *gorm.DB
-	conns map[string]*gorm.DB
-)
+const defaultName = "default"
+
+// 未配置connect_timeout且uri中未设置timeout时建立连接的超时时间
+const defaultConnectTimeout = 5 * time.Second
 
+// DB 获取数据库连接，未指定或指定的数据库未配置时返回default，连接失败时panic，需要处理错误时使用Get
 func DB(dbName ...string) *gorm.DB {
-	if db == nil && len(conns) == 0 {
-		panic(errors.New("mysql is not ready"))
+	name := defaultName
+	if len(dbName) > 0 && container.Has[*gorm.DB](container.Default, dbName[0]) {
+		name = dbName[0]
 	}
-	if len(dbName) > 0 {
-		if conn, ok := conns[dbName[0]]; ok {
-			return conn
-		}
+	conn, err := Get(name)
+	if err != nil {
+		panic(fmt.Errorf("mysql is not ready: %w", err))
 	}
-	return db
+	return conn
 }
 
-func init() {
-	err := InitMysql(cfg.AppConf)
-	if err != nil {
-		panic(fmt.Sprintf("init mysql failed: %s", err))
-	}
+// Get 获取数据库连接，首次获取时建立连接
+func Get(name string) (*gorm.DB, error) {
+	return container.Resolve[*gorm.DB](container.Default, name)
 }
 
-// InitMysql 初始化数据库
+// InitMysql 注册配置的数据库，连接在首次使用时建立
 func InitMysql(cfg *cfg.AppConfig) error {
-	conns = make(map[string]*gorm.DB)
 	for dbKey, dbConfig := range cfg.DbsConfig {
-		conn, err := openConn(dbConfig.Uri, dbConfig.MaxIdleConn, dbConfig.MaxOpenConn, dbConfig.ConnectLifeTime, dbConfig.ConnectIdleTime)
-		if err != nil {
-			return fmt.Errorf("open connection failed, error: %s", err.Error())
-		}
-		conns[dbKey] = conn
-		if dbKey == "default" {
-			db = conn
-		}
+		dbConfig := dbConfig
+		container.Provide(container.Default, dbKey, func() (*gorm.DB, error) {
+			conn, err := openConn(dbConfig.Uri, dbConfig.MaxIdleConn, dbConfig.MaxOpenConn, dbConfig.ConnectLifeTime, dbConfig.ConnectIdleTime, dbConfig.ConnectTimeout)
+			if err != nil {
+				return nil, fmt.Errorf("open connection failed, error: %s", err.Error())
+			}
+			return conn, nil
+		})
 	}
 	return nil
 }
 
-func openConn(url string, idle, open, lifeTime, idleTime int) (*gorm.DB, error) {
+// Register 使用指定的驱动打开连接并以name注册，name为default时作为默认连接，用于测试中替换为sqlite等实现
+func Register(name string, dialector gorm.Dialector) (*gorm.DB, error) {
+	conn, err := gorm.Open(dialector, newGormConfig())
+	if err != nil {
+		return nil, err
+	}
+	container.Override(container.Default, name, conn)
+	return conn, nil
+}
+
+func newGormConfig() *gorm.Config {
 	newLogger := zapgorm2.New(log.SqlLogger)
 	newLogger.SetAsDefault()
-	openDB, err := gorm.Open(mysql.New(mysql.Config{DSN: url}), &gorm.Config{
+	return &gorm.Config{
 		Logger:         newLogger,
 		NamingStrategy: schema.NamingStrategy{SingularTable: true},
 		NowFunc: func() time.Time {
 			return time.Now().Local()
 		},
-	})
+	}
+}
+
+func openConn(url string, idle, open, lifeTime, idleTime, connectTimeout int) (*gorm.DB, error) {
+	dsn, err := withTimeout(url, connectTimeout)
+	if err != nil {
+		return nil, err
+	}
+	openDB, err := gorm.Open(mysql.New(mysql.Config{DSN: dsn}), newGormConfig())
 	if err != nil {
 		return nil, err
 	}
@@ -82,3 +101,56 @@ func openConn(url string, idle, open, lifeTime, idleTime int) (*gorm.DB, error)
 	}
 	return openDB, nil
 }
+
+// uri中未设置timeout时设置建立连接的超时时间，避免数据库不可达时长时间阻塞
+func withTimeout(url string, connectTimeout int) (string, error) {
+	dsnConfig, err := mysqldriver.ParseDSN(url)
+	if err != nil {
+		return "", err
+	}
+	if dsnConfig.Timeout > 0 {
+		return url, nil
+	}
+	dsnConfig.Timeout = defaultConnectTimeout
+	if connectTimeout > 0 {
+		dsnConfig.Timeout = time.Second * time.Duration(connectTimeout)
+	}
+	return dsnConfig.FormatDSN(), nil
+}
+
+// Names 获取所有已配置的数据库名称
+func Names() []string {
+	return container.Names[*gorm.DB](container.Default)
+}
+
+// Opened 获取已建立连接的sql.DB，不会触发建立连接
+func Opened(name string) (*sql.DB, bool) {
+	conn, ok := container.Resolved[*gorm.DB](container.Default, name)
+	if !ok {
+		return nil, false
+	}
+	sqlDB, err := conn.DB()
+	return sqlDB, err == nil
+}
+
+// Ping 检查数据库连接是否可用，尚未建立连接时先建立连接，耗时受connect_timeout限制
+func Ping(ctx context.Context, dbName string) error {
+	conn, err := Get(dbName)
+	if err != nil {
+		return err
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Close 关闭所有已建立的数据库连接
+func Close() {
+	for _, name := range Names() {
+		if sqlDB, ok := Opened(name); ok {
+			_ = sqlDB.Close()
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/easonchen147/foundation/cfg"
+	"github.com/easonchen147/foundation/container"
+	"github.com/easonchen147/foundation/health"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestWithTimeoutSetsDefault(t *testing.T) {
+	dsn, err := withTimeout("user:pass@tcp(127.0.0.1:3306)/app", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dsnConfig, _ := mysql.ParseDSN(dsn); dsnConfig.Timeout != defaultConnectTimeout {
+		t.Errorf("timeout = %v, want %v", dsnConfig.Timeout, defaultConnectTimeout)
+	}
+}
+
+func TestWithTimeoutKeepsUriTimeout(t *testing.T) {
+	dsn, err := withTimeout("user:pass@tcp(127.0.0.1:3306)/app?timeout=1s", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dsnConfig, _ := mysql.ParseDSN(dsn); dsnConfig.Timeout != time.Second {
+		t.Errorf("timeout = %v, want 1s", dsnConfig.Timeout)
+	}
+}
+
+// 尚未建立连接的数据库不可达时，就绪检查失败
+func TestReadinessFailsWhenUnreachable(t *testing.T) {
+	t.Cleanup(container.Default.Reset)
+	t.Cleanup(health.Reset)
+	config, err := cfg.LoadConfigMap(map[string]interface{}{
+		"dbs.default.uri": "user:pass@tcp(127.0.0.1:1)/app?timeout=1s",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = InitMysql(config); err != nil {
+		t.Fatal(err)
+	}
+	health.Register(health.NewChecker("mysql:default", func(ctx context.Context) error {
+		return Ping(ctx, defaultName)
+	}))
+
+	if report := health.Check(context.Background()); report.Status != health.StatusDown {
+		t.Errorf("readiness = %s, want %s", report.Status, health.StatusDown)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build cgo
+
+package foundationtest
+
+// sqlite驱动依赖cgo
+const cgoEnabled = true
@@ -0,0 +1,183 @@
+// Package foundationtest 在测试中启动应用，redis使用miniredis，mysql使用内存sqlite，kafka使用内存Bus，
+// 并捕获业务日志与访问日志，其他组件可以通过container.Override替换。组件与日志均为全局对象，使用该包的测试不能并行执行。
+// sqlite驱动gorm.io/driver/sqlite依赖cgo，需要CGO_ENABLED=1及gcc，未启用cgo时通过WithDatabases()不创建数据库。
+// 内存kafka只支持producer，kafka consumer不在该包的支持范围内，配置kafka.consumers时New直接失败，
+// 消费逻辑通过Env.Kafka.Messages或Subscribe获取写入的消息后直接调用。
+package foundationtest
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/easonchen147/foundation"
+	"github.com/easonchen147/foundation/cfg"
+	"github.com/easonchen147/foundation/container"
+	"github.com/easonchen147/foundation/db"
+	"github.com/easonchen147/foundation/health"
+	"github.com/easonchen147/foundation/kafka"
+	"github.com/easonchen147/foundation/log"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var dbSeq atomic.Int64
+
+// Env 测试环境
+type Env struct {
+	Config *cfg.AppConfig
+	App    *foundation.App
+	Server *httptest.Server
+
+	Redis *miniredis.Miniredis
+	DBs   map[string]*gorm.DB // key为数据库名称，默认只有default
+	Kafka *Bus
+
+	Logs       *observer.ObservedLogs // 业务日志
+	AccessLogs *observer.ObservedLogs // 访问日志
+	SqlLogs    *observer.ObservedLogs // sql日志
+}
+
+// Option 测试环境选项
+type Option func(*options)
+
+type options struct {
+	settings   map[string]interface{}
+	databases  []string
+	appOptions []foundation.Option
+}
+
+// WithSettings 设置配置项，key可以是"ts.expire"形式的路径或嵌套的map，
+// kafka producer通过"kafka.producers.<name>.topic"配置，broker地址无需真实存在
+func WithSettings(settings map[string]interface{}) Option {
+	return func(o *options) {
+		for key, value := range settings {
+			o.settings[key] = value
+		}
+	}
+}
+
+// WithDatabases 指定需要创建的数据库名称，默认只创建default，不传参数时不创建数据库
+func WithDatabases(names ...string) Option {
+	return func(o *options) {
+		o.databases = names
+	}
+}
+
+// WithAppOptions 传递额外的应用构建选项
+func WithAppOptions(opts ...foundation.Option) Option {
+	return func(o *options) {
+		o.appOptions = append(o.appOptions, opts...)
+	}
+}
+
+// New 创建测试环境并启动httptest.Server，测试结束时自动释放
+func New(t testing.TB, registerRoutes func(*gin.Engine), opts ...Option) *Env {
+	t.Helper()
+	o := &options{
+		settings:  map[string]interface{}{"env": cfg.Test},
+		databases: []string{"default"},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	env := &Env{DBs: make(map[string]*gorm.DB), Kafka: NewBus()}
+	// 最后执行，清除测试中注册及替换的组件，以及健康检查和关闭状态
+	t.Cleanup(container.Default.Reset)
+	t.Cleanup(health.Reset)
+	health.Reset()
+	var err error
+	if env.Redis, err = miniredis.Run(); err != nil {
+		t.Fatalf("foundationtest: start miniredis failed: %v", err)
+	}
+	t.Cleanup(env.Redis.Close)
+	o.settings["redis.addr"] = env.Redis.Addr()
+
+	if env.Config, err = cfg.LoadConfigMap(o.settings); err != nil {
+		t.Fatalf("foundationtest: %v", err)
+	}
+	if env.Config.KafkaConfig != nil && len(env.Config.KafkaConfig.Consumers) > 0 {
+		t.Fatalf("foundationtest: kafka.consumers is out of scope, the in-memory kafka only serves producers, " +
+			"read produced messages with Env.Kafka.Messages or Env.Kafka.Subscribe instead")
+	}
+	if len(o.databases) > 0 && !cgoEnabled {
+		t.Fatalf("foundationtest: sqlite requires cgo, run tests with CGO_ENABLED=1 or use WithDatabases() to skip databases")
+	}
+
+	appOptions := append([]foundation.Option{
+		foundation.WithConfig(env.Config),
+		foundation.WithRoutes(registerRoutes),
+		foundation.WithComponents(foundation.ComponentRedis),
+	}, o.appOptions...)
+	if env.App, err = foundation.New(appOptions...); err != nil {
+		t.Fatalf("foundationtest: create app failed: %v", err)
+	}
+	// 应用创建时会按配置初始化日志，这里替换为可断言的日志
+	env.captureLogs(t)
+
+	for _, name := range o.databases {
+		dsn := fmt.Sprintf("file:foundationtest_%s_%d?mode=memory&cache=shared", name, dbSeq.Add(1))
+		conn, err := db.Register(name, sqlite.Open(dsn))
+		if err != nil {
+			t.Fatalf("foundationtest: open sqlite %s failed: %v", name, err)
+		}
+		// sqlite不支持并发写入
+		if sqlDB, err := conn.DB(); err == nil {
+			sqlDB.SetMaxOpenConns(1)
+		}
+		env.DBs[name] = conn
+		name := name
+		env.App.RegisterChecker(health.NewChecker("mysql:"+name, func(ctx context.Context) error {
+			return db.Ping(ctx, name)
+		}))
+	}
+	t.Cleanup(db.Close)
+
+	if env.Config.KafkaConfig != nil {
+		if err = kafka.InitProducer(env.Config); err != nil {
+			t.Fatalf("foundationtest: init kafka producer failed: %v", err)
+		}
+		for name := range env.Config.KafkaConfig.Producers {
+			env.Kafka.Attach(kafka.Producer(name))
+		}
+		t.Cleanup(kafka.Close)
+	}
+
+	env.Server = httptest.NewServer(env.App.Engine())
+	t.Cleanup(env.Server.Close)
+	return env
+}
+
+// DB 获取默认数据库
+func (e *Env) DB() *gorm.DB {
+	return e.DBs["default"]
+}
+
+// URL 拼接测试服务的请求地址
+func (e *Env) URL(path string) string {
+	return e.Server.URL + path
+}
+
+func (e *Env) captureLogs(t testing.TB) {
+	logger, accessLogger, sqlLogger := log.Logger, log.AccessLogger, log.SqlLogger
+	t.Cleanup(func() {
+		log.Logger, log.AccessLogger, log.SqlLogger = logger, accessLogger, sqlLogger
+	})
+
+	var core zapcore.Core
+	core, e.Logs = observer.New(zapcore.DebugLevel)
+	log.Logger = zap.New(core)
+	core, e.AccessLogs = observer.New(zapcore.DebugLevel)
+	log.AccessLogger = zap.New(core)
+	core, e.SqlLogs = observer.New(zapcore.DebugLevel)
+	log.SqlLogger = zap.New(core)
+}
@@ -0,0 +1,93 @@
+package foundationtest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/easonchen147/foundation/health"
+	"github.com/easonchen147/foundation/kafka"
+
+	"github.com/gin-gonic/gin"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// 记录Fatalf的信息并结束当前goroutine
+type fatalTB struct {
+	testing.TB
+	msg string
+}
+
+func (f *fatalTB) Fatalf(format string, args ...interface{}) {
+	f.msg = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+func TestNewRejectsKafkaConsumers(t *testing.T) {
+	tb := &fatalTB{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		New(tb, func(*gin.Engine) {}, WithSettings(map[string]interface{}{
+			"kafka.consumers.orders.broker": "localhost:9092",
+			"kafka.consumers.orders.topic":  "orders",
+			"kafka.consumers.orders.group":  "app",
+		}))
+	}()
+	<-done
+	if !strings.Contains(tb.msg, "kafka.consumers is out of scope") {
+		t.Errorf("New() fatal = %q, want kafka.consumers error", tb.msg)
+	}
+}
+
+func TestProducer(t *testing.T) {
+	if !cgoEnabled {
+		t.Skip("sqlite requires cgo")
+	}
+	env := New(t, func(*gin.Engine) {}, WithSettings(map[string]interface{}{
+		"kafka.producers.orders.broker": "localhost:9092",
+		"kafka.producers.orders.topic":  "orders",
+	}))
+	ctx := context.Background()
+	if err := kafka.Producer("orders").WriteMessages(ctx, kafkago.Message{Key: []byte("1"), Value: []byte("created")}); err != nil {
+		t.Fatal(err)
+	}
+	msgs := env.Kafka.Messages("orders")
+	if len(msgs) != 1 || string(msgs[0].Value) != "created" {
+		t.Fatalf("Messages() = %v, want one created message", msgs)
+	}
+
+	if err := env.DB().Exec("CREATE TABLE orders (id INTEGER PRIMARY KEY)").Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 前一个测试环境进入关闭状态后，新的测试环境仍然就绪
+func TestNewResetsShuttingDown(t *testing.T) {
+	t.Run("shutdown", func(t *testing.T) {
+		env := New(t, func(*gin.Engine) {}, WithDatabases())
+		health.MarkShuttingDown()
+		if got := readyz(t, env); got != http.StatusServiceUnavailable {
+			t.Errorf("GET /readyz = %d, want 503", got)
+		}
+	})
+	t.Run("next", func(t *testing.T) {
+		env := New(t, func(*gin.Engine) {}, WithDatabases())
+		if got := readyz(t, env); got != http.StatusOK {
+			t.Errorf("GET /readyz = %d, want 200", got)
+		}
+	})
+}
+
+func readyz(t *testing.T, env *Env) int {
+	t.Helper()
+	resp, err := http.Get(env.URL("/readyz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
@@ -0,0 +1,176 @@
+package foundationtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/produce"
+)
+
+// Bus 内存中的kafka，接管producer的网络请求并保存写入的消息，每个topic只有一个分区
+type Bus struct {
+	mu       sync.Mutex
+	messages map[string][]kafka.Message
+	subs     map[string][]chan kafka.Message
+}
+
+// NewBus 创建内存kafka
+func NewBus() *Bus {
+	return &Bus{
+		messages: make(map[string][]kafka.Message),
+		subs:     make(map[string][]chan kafka.Message),
+	}
+}
+
+// Attach 将producer的请求转发到内存kafka，并缩短批量发送的等待时间
+func (b *Bus) Attach(writer *kafka.Writer) {
+	writer.Transport = b
+	writer.BatchTimeout = time.Millisecond
+}
+
+// Messages 获取topic中已写入的消息
+func (b *Bus) Messages(topic string) []kafka.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]kafka.Message(nil), b.messages[topic]...)
+}
+
+// Subscribe 订阅topic中新写入的消息，ctx取消后关闭channel
+func (b *Bus) Subscribe(ctx context.Context, topic string) <-chan kafka.Message {
+	ch := make(chan kafka.Message, 128)
+	b.mu.Lock()
+	b.subs[topic] = append(b.subs[topic], ch)
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		subs := b.subs[topic]
+		for i, sub := range subs {
+			if sub == ch {
+				b.subs[topic] = append(subs[:i], subs[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// Publish 直接向topic写入消息
+func (b *Bus) Publish(topic string, msgs ...kafka.Message) {
+	b.append(topic, msgs)
+}
+
+// 写入消息并通知订阅者，返回第一条消息的offset
+func (b *Bus) append(topic string, msgs []kafka.Message) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	baseOffset := int64(len(b.messages[topic]))
+	for _, msg := range msgs {
+		msg.Topic = topic
+		msg.Offset = int64(len(b.messages[topic]))
+		if msg.Time.IsZero() {
+			msg.Time = time.Now()
+		}
+		b.messages[topic] = append(b.messages[topic], msg)
+		for _, sub := range b.subs[topic] {
+			select {
+			case sub <- msg:
+			default: // 订阅者处理不过来时丢弃，避免阻塞写入
+			}
+		}
+	}
+	return baseOffset
+}
+
+// RoundTrip 实现kafka.RoundTripper，仅支持producer使用的metadata与produce请求
+func (b *Bus) RoundTrip(ctx context.Context, addr net.Addr, req kafka.Request) (kafka.Response, error) {
+	switch req := req.(type) {
+	case *metadata.Request:
+		return b.metadata(addr, req), nil
+	case *produce.Request:
+		return b.produce(req)
+	default:
+		return nil, fmt.Errorf("foundationtest: unsupported kafka request %T", req)
+	}
+}
+
+func (b *Bus) metadata(addr net.Addr, req *metadata.Request) *metadata.Response {
+	host, port := "localhost", 9092
+	if addr != nil {
+		if h, p, err := net.SplitHostPort(addr.String()); err == nil {
+			host = h
+			_, _ = fmt.Sscanf(p, "%d", &port)
+		}
+	}
+	resp := &metadata.Response{
+		Brokers: []metadata.ResponseBroker{{NodeID: 0, Host: host, Port: int32(port)}},
+	}
+	for _, topic := range req.TopicNames {
+		resp.Topics = append(resp.Topics, metadata.ResponseTopic{
+			Name: topic,
+			Partitions: []metadata.ResponsePartition{{
+				PartitionIndex: 0,
+				LeaderID:       0,
+				ReplicaNodes:   []int32{0},
+				IsrNodes:       []int32{0},
+			}},
+		})
+	}
+	return resp
+}
+
+func (b *Bus) produce(req *produce.Request) (*produce.Response, error) {
+	resp := &produce.Response{}
+	for _, topic := range req.Topics {
+		respTopic := produce.ResponseTopic{Topic: topic.Topic}
+		for _, partition := range topic.Partitions {
+			msgs, err := readMessages(partition.RecordSet.Records)
+			if err != nil {
+				return nil, err
+			}
+			baseOffset := b.append(topic.Topic, msgs)
+			respTopic.Partitions = append(respTopic.Partitions, produce.ResponsePartition{
+				Partition:  partition.Partition,
+				BaseOffset: baseOffset,
+			})
+		}
+		resp.Topics = append(resp.Topics, respTopic)
+	}
+	return resp, nil
+}
+
+func readMessages(records protocol.RecordReader) ([]kafka.Message, error) {
+	var msgs []kafka.Message
+	for {
+		record, err := records.ReadRecord()
+		if err == io.EOF {
+			return msgs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		key, err := protocol.ReadAll(record.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := protocol.ReadAll(record.Value)
+		if err != nil {
+			return nil, err
+		}
+		msg := kafka.Message{Key: key, Value: value, Time: record.Time}
+		for _, header := range record.Headers {
+			msg.Headers = append(msg.Headers, kafka.Header{Key: header.Key, Value: header.Value})
+		}
+		msgs = append(msgs, msg)
+	}
+}
@@ -0,0 +1,57 @@
+package foundationtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// LogEntries 获取消息中包含msg的业务日志
+func (e *Env) LogEntries(msg string) []observer.LoggedEntry {
+	return e.Logs.FilterMessageSnippet(msg).AllUntimed()
+}
+
+// AssertLogged 断言存在消息中包含msg的业务日志
+func (e *Env) AssertLogged(t testing.TB, msg string) {
+	t.Helper()
+	if len(e.LogEntries(msg)) == 0 {
+		t.Errorf("no log entry contains %q, captured:\n%s", msg, dump(e.Logs))
+	}
+}
+
+// AssertNotLogged 断言不存在消息中包含msg的业务日志
+func (e *Env) AssertNotLogged(t testing.TB, msg string) {
+	t.Helper()
+	if entries := e.LogEntries(msg); len(entries) > 0 {
+		t.Errorf("unexpected log entry contains %q: %s", msg, entries[0].Message)
+	}
+}
+
+// AccessEntries 获取指定请求方式及路径的访问日志
+func (e *Env) AccessEntries(method, path string) []observer.LoggedEntry {
+	return e.AccessLogs.Filter(func(entry observer.LoggedEntry) bool {
+		fields := entry.ContextMap()
+		return fields["method"] == method && fields["path"] == path
+	}).AllUntimed()
+}
+
+// AssertAccessLogged 断言存在指定请求方式、路径及状态码的访问日志
+func (e *Env) AssertAccessLogged(t testing.TB, method, path string, code int) {
+	t.Helper()
+	for _, entry := range e.AccessEntries(method, path) {
+		if entry.ContextMap()["code"] == int64(code) {
+			return
+		}
+	}
+	t.Errorf("no access log entry for %s %s with code %d, captured:\n%s", method, path, code, dump(e.AccessLogs))
+}
+
+func dump(logs *observer.ObservedLogs) string {
+	var builder strings.Builder
+	for _, entry := range logs.AllUntimed() {
+		fmt.Fprintf(&builder, "  %s %s %v\n", entry.Level.CapitalString(), entry.Message, entry.ContextMap())
+	}
+	return builder.String()
+}
@@ -0,0 +1,185 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	// 单个检查项的超时时间
+	checkTimeout = 3 * time.Second
+)
+
+// Checker 健康检查项
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type checkerFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (c *checkerFunc) Name() string {
+	return c.name
+}
+
+func (c *checkerFunc) Check(ctx context.Context) error {
+	return c.fn(ctx)
+}
+
+// NewChecker 使用函数创建检查项
+func NewChecker(name string, fn func(ctx context.Context) error) Checker {
+	return &checkerFunc{name: name, fn: fn}
+}
+
+// ComponentStatus 单个检查项的检查结果
+type ComponentStatus struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Report 健康检查报告
+type Report struct {
+	Status     string            `json:"status"`
+	Components []ComponentStatus `json:"components"`
+}
+
+var (
+	mu       sync.RWMutex
+	checkers []Checker
+
+	shuttingDown atomic.Bool
+)
+
+// Register 注册检查项，同名检查项会被替换
+func Register(newCheckers ...Checker) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, checker := range newCheckers {
+		replaced := false
+		for i, existed := range checkers {
+			if existed.Name() == checker.Name() {
+				checkers[i] = checker
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			checkers = append(checkers, checker)
+		}
+	}
+}
+
+// Reset 移除所有检查项并清除停止标记，应用创建时调用，避免上一个应用的状态影响新的应用
+func Reset() {
+	mu.Lock()
+	checkers = nil
+	mu.Unlock()
+	shuttingDown.Store(false)
+}
+
+// MarkShuttingDown 标记服务正在停止，readiness检查会立即失败
+func MarkShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+// ShuttingDown 服务是否正在停止
+func ShuttingDown() bool {
+	return shuttingDown.Load()
+}
+
+// Check 并发执行所有检查项
+func Check(ctx context.Context) *Report {
+	mu.RLock()
+	current := make([]Checker, len(checkers))
+	copy(current, checkers)
+	mu.RUnlock()
+
+	report := &Report{Status: StatusUp, Components: make([]ComponentStatus, len(current))}
+	var wg sync.WaitGroup
+	for i, checker := range current {
+		wg.Add(1)
+		go func(i int, checker Checker) {
+			defer wg.Done()
+			report.Components[i] = check(ctx, checker)
+		}(i, checker)
+	}
+	wg.Wait()
+
+	for _, component := range report.Components {
+		if component.Status != StatusUp {
+			report.Status = StatusDown
+			break
+		}
+	}
+	return report
+}
+
+// 检查项未在超时时间内返回时直接判定失败，不等待其返回
+func check(ctx context.Context, checker Checker) ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() {
+		result <- checker.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	status := ComponentStatus{
+		Name:    checker.Name(),
+		Status:  StatusUp,
+		Latency: time.Since(start).String(),
+	}
+	if err != nil {
+		status.Status = StatusDown
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// Liveness 存活检查，只要进程可以响应即返回200，不执行依赖的检查项，避免依赖故障导致服务被重启
+func Liveness() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, &Report{Status: StatusUp, Components: []ComponentStatus{}})
+	}
+}
+
+// Readiness 就绪检查，服务停止中或任意检查项失败时返回503
+func Readiness() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ShuttingDown() {
+			c.JSON(http.StatusServiceUnavailable, &Report{Status: StatusDown, Components: []ComponentStatus{}})
+			return
+		}
+		report := Check(c.Request.Context())
+		if report.Status != StatusUp {
+			c.JSON(http.StatusServiceUnavailable, report)
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// RegisterRoutes 注册/healthz与/readyz路由
+func RegisterRoutes(router gin.IRoutes) {
+	router.GET("/healthz", Liveness())
+	router.GET("/readyz", Readiness())
+}
@@ -0,0 +1,53 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func serve(path string) int {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterRoutes(router)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w.Code
+}
+
+func registerDown(t *testing.T) {
+	t.Cleanup(Reset)
+	Register(NewChecker("redis", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}))
+}
+
+func TestLivenessIgnoresDependencies(t *testing.T) {
+	registerDown(t)
+	if got := serve("/healthz"); got != http.StatusOK {
+		t.Errorf("GET /healthz = %d, want 200", got)
+	}
+}
+
+func TestReadinessFailsWhenDependencyDown(t *testing.T) {
+	registerDown(t)
+	if got := serve("/readyz"); got != http.StatusServiceUnavailable {
+		t.Errorf("GET /readyz = %d, want 503", got)
+	}
+}
+
+func TestResetClearsShuttingDown(t *testing.T) {
+	t.Cleanup(Reset)
+	MarkShuttingDown()
+	if got := serve("/readyz"); got != http.StatusServiceUnavailable {
+		t.Fatalf("GET /readyz while shutting down = %d, want 503", got)
+	}
+	Reset()
+	if got := serve("/readyz"); got != http.StatusOK {
+		t.Errorf("GET /readyz after Reset = %d, want 200", got)
+	}
+}
@@ -1,80 +1,127 @@
 package kafka
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/easonchen147/foundation/cfg"
+	"github.com/easonchen147/foundation/container"
 
 	"github.com/segmentio/kafka-go"
 )
 
+// 已配置的broker地址，用于健康检查
 var (
-	producers map[string]*kafka.Writer
-	consumers map[string]*kafka.Reader
+	mu      sync.Mutex
+	brokers = make(map[string]struct{})
 )
 
-func init() {
-	err := InitProducer(cfg.AppConf)
-	if err != nil {
-		panic(fmt.Sprintf("init kafka producer failed: %s", err))
-	}
-
-	err = InitConsumer(cfg.AppConf)
-	if err != nil {
-		panic(fmt.Sprintf("init kafka consumer failed: %s", err))
-	}
-}
-
+// InitProducer 注册配置的producer，在首次使用时创建
 func InitProducer(cfg *cfg.AppConfig) error {
 	if cfg.KafkaConfig == nil {
 		return nil
 	}
-	producers = make(map[string]*kafka.Writer)
 	for name, kafkaCfg := range cfg.KafkaConfig.Producers {
-		producers[name] = &kafka.Writer{
-			Addr:     kafka.TCP(kafkaCfg.Broker),
-			Topic:    kafkaCfg.Topic,
-			Balancer: &kafka.LeastBytes{},
-		}
+		kafkaCfg := kafkaCfg
+		addBroker(kafkaCfg.Broker)
+		container.Provide(container.Default, name, func() (*kafka.Writer, error) {
+			return &kafka.Writer{
+				Addr:     kafka.TCP(kafkaCfg.Broker),
+				Topic:    kafkaCfg.Topic,
+				Balancer: &kafka.LeastBytes{},
+			}, nil
+		})
 	}
 	return nil
 }
 
+// InitConsumer 注册配置的consumer，在首次使用时创建
 func InitConsumer(cfg *cfg.AppConfig) error {
 	if cfg.KafkaConfig == nil {
 		return nil
 	}
 	for name, kafkaCfg := range cfg.KafkaConfig.Consumers {
-		consumers[name] = kafka.NewReader(kafka.ReaderConfig{
-			Brokers:   []string{kafkaCfg.Broker},
-			GroupID:   kafkaCfg.Group,
-			Topic:     kafkaCfg.Topic,
-			Partition: kafkaCfg.Partition,
+		kafkaCfg := kafkaCfg
+		addBroker(kafkaCfg.Broker)
+		container.Provide(container.Default, name, func() (*kafka.Reader, error) {
+			return kafka.NewReader(kafka.ReaderConfig{
+				Brokers:   []string{kafkaCfg.Broker},
+				GroupID:   kafkaCfg.Group,
+				Topic:     kafkaCfg.Topic,
+				Partition: kafkaCfg.Partition,
+			}), nil
 		})
 	}
 	return nil
 }
 
+func addBroker(broker string) {
+	mu.Lock()
+	defer mu.Unlock()
+	brokers[broker] = struct{}{}
+}
+
+// Producer 获取producer，未配置时panic，需要处理错误时使用GetProducer
 func Producer(name string) *kafka.Writer {
-	if len(producers) == 0 {
-		panic(errors.New("kafka producer is not ready"))
+	producer, err := GetProducer(name)
+	if err != nil {
+		panic(fmt.Errorf("kafka producer is not ready: %w", err))
 	}
-	return producers[name]
+	return producer
+}
+
+// GetProducer 获取producer
+func GetProducer(name string) (*kafka.Writer, error) {
+	return container.Resolve[*kafka.Writer](container.Default, name)
 }
 
+// Writer 获取consumer，未配置时panic，需要处理错误时使用GetConsumer
 func Writer(name string) *kafka.Reader {
-	if len(consumers) == 0 {
-		panic(errors.New("kafka consumer is not ready"))
+	consumer, err := GetConsumer(name)
+	if err != nil {
+		panic(fmt.Errorf("kafka consumer is not ready: %w", err))
+	}
+	return consumer
+}
+
+// GetConsumer 获取consumer
+func GetConsumer(name string) (*kafka.Reader, error) {
+	return container.Resolve[*kafka.Reader](container.Default, name)
+}
+
+// Brokers 获取所有已配置的broker地址
+func Brokers() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	result := make([]string, 0, len(brokers))
+	for broker := range brokers {
+		result = append(result, broker)
 	}
-	return consumers[name]
+	sort.Strings(result)
+	return result
 }
 
+// Ping 检查broker是否可以连接
+func Ping(ctx context.Context, broker string) error {
+	conn, err := kafka.DialContext(ctx, "tcp", broker)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// Close 关闭已创建的producer与consumer
 func Close() {
-	for _, producer := range producers {
-		_ = producer.Close()
+	for _, name := range container.Names[*kafka.Writer](container.Default) {
+		if producer, ok := container.Resolved[*kafka.Writer](container.Default, name); ok {
+			_ = producer.Close()
+		}
 	}
-	for _, consumer := range consumers {
-		_ = consumer.Close()
+	for _, name := range container.Names[*kafka.Reader](container.Default) {
+		if consumer, ok := container.Resolved[*kafka.Reader](container.Default, name); ok {
+			_ = consumer.Close()
+		}
 	}
 }
@@ -0,0 +1,221 @@
+package foundation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/easonchen147/foundation/log"
+	"github.com/easonchen147/foundation/rpc"
+)
+
+// 内置启动步骤的顺序，自定义钩子可以通过Order插入到任意步骤之间
+const (
+	StartOrderAdmin  = 100 // 启动管理端口
+	StartOrderHttp   = 200 // 启动http服务
+	StartOrderWorker = 300 // 启动后台任务
+)
+
+// 内置停止步骤的顺序，自定义钩子可以通过Order插入到任意步骤之间
+const (
+	StopOrderTraffic = 100 // 停止接收流量
+	StopOrderConn    = 150 // 关闭WebSocket、SSE等长连接，避免阻塞http请求的排空
+	StopOrderHttp    = 200 // 排空http请求
+	StopOrderWorker  = 300 // 停止后台任务
+	StopOrderModule  = 330 // 按初始化的相反顺序关闭模块
+	StopOrderAdmin   = 350 // 关闭管理端口，保证停止过程中仍可以查看指标与健康状态
+	StopOrderClient  = 400 // 关闭数据客户端
+	StopOrderLogger  = 500 // 刷新日志
+)
+
+// Hook 生命周期钩子
+type Hook struct {
+	Name    string
+	Order   int           // 执行顺序，越小越先执行，相同时按注册顺序执行
+	Timeout time.Duration // 超时时间，为0时使用所在步骤的默认超时时间
+	Fn      func(ctx context.Context) error
+}
+
+// OnStart 注册启动钩子，任意钩子失败都会中止启动
+func (app *App) OnStart(hook Hook) {
+	app.startHooks = append(app.startHooks, hook)
+}
+
+// OnStop 注册停止钩子，钩子失败不会中止后续钩子的执行
+func (app *App) OnStop(hook Hook) {
+	app.stopHooks = append(app.stopHooks, hook)
+}
+
+// 注册内置的启动、停止步骤
+func (app *App) registerBuiltinHooks() {
+	if app.adminServer != nil {
+		app.OnStart(Hook{Name: "admin", Order: StartOrderAdmin, Fn: func(ctx context.Context) error {
+			return app.serve(app.adminServer)
+		}})
+	}
+	app.OnStart(Hook{Name: "http", Order: StartOrderHttp, Fn: func(ctx context.Context) error {
+		if app.certReloader != nil {
+			go app.certReloader.Watch(app.ctx)
+		}
+		if err := app.serve(app.server); err != nil {
+			return err
+		}
+		app.serving.Store(true)
+		return nil
+	}})
+	if app.grpcServer != nil && app.grpcMux == nil {
+		app.OnStart(Hook{Name: "grpc", Order: StartOrderHttp, Fn: func(ctx context.Context) error {
+			return app.serveGrpc()
+		}})
+	}
+	app.OnStart(Hook{Name: "worker", Order: StartOrderWorker, Fn: func(ctx context.Context) error {
+		app.workers.Start(app.ctx)
+		return nil
+	}})
+
+	wait := time.Second * time.Duration(app.config.ShutdownConfig.WaitBeforeDrain)
+	app.OnStop(Hook{Name: "traffic", Order: StopOrderTraffic, Timeout: wait + time.Second, Fn: func(ctx context.Context) error {
+		// 未启动http服务时(如执行命令行任务)无需等待负载均衡摘除流量
+		if !app.serving.Load() {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		return nil
+	}})
+	app.OnStop(Hook{Name: "http", Order: StopOrderHttp, Fn: func(ctx context.Context) error {
+		return drain(ctx, app.server)
+	}})
+	if app.grpcServer != nil {
+		app.OnStop(Hook{Name: "grpc", Order: StopOrderHttp, Fn: func(ctx context.Context) error {
+			if app.grpcMux != nil {
+				return app.grpcMux.Drain(ctx)
+			}
+			return rpc.GracefulStop(ctx, app.grpcServer)
+		}})
+	}
+	app.OnStop(Hook{Name: "worker", Order: StopOrderWorker, Fn: app.workers.Stop})
+	for i := len(app.modules) - 1; i >= 0; i-- {
+		module := app.modules[i]
+		app.OnStop(Hook{Name: "module:" + module.Name(), Order: StopOrderModule, Fn: module.Close})
+	}
+	if app.adminServer != nil {
+		app.OnStop(Hook{Name: "admin", Order: StopOrderAdmin, Timeout: shutdownSeconds(app.config.ShutdownConfig.HttpTimeout, 10), Fn: func(ctx context.Context) error {
+			return drain(ctx, app.adminServer)
+		}})
+	}
+	for _, component := range app.opts.components {
+		component := component
+		app.OnStop(Hook{Name: string(component), Order: StopOrderClient, Fn: func(ctx context.Context) error {
+			closeComponent(component)
+			return nil
+		}})
+	}
+	app.OnStop(Hook{Name: "logger", Order: StopOrderLogger, Fn: func(ctx context.Context) error {
+		log.Close()
+		// 控制台输出时Sync会返回无意义的错误，这里只做打印
+		if err := log.Logger.Sync(); err != nil {
+			fmt.Printf("Failed to close logger: %s\n", err)
+		}
+		if err := log.AccessLogger.Sync(); err != nil {
+			fmt.Printf("Failed to close access logger: %s\n", err)
+		}
+		return nil
+	}})
+}
+
+// 执行启动钩子，遇到错误立即返回
+func (app *App) runStartHooks(ctx context.Context) error {
+	for _, hook := range sortHooks(app.startHooks) {
+		if err := runHook(ctx, hook, app.hookTimeout(hook)); err != nil {
+			log.Error(ctx, "Start hook %s failed, error: %v", hook.Name, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// 执行停止钩子，收集所有错误
+func (app *App) runStopHooks(ctx context.Context) error {
+	var errs []error
+	for _, hook := range sortHooks(app.stopHooks) {
+		if err := runHook(ctx, hook, app.stopHookTimeout(hook)); err != nil {
+			log.Error(ctx, "Stop hook %s failed, error: %v", hook.Name, err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (app *App) hookTimeout(hook Hook) time.Duration {
+	if hook.Timeout > 0 {
+		return hook.Timeout
+	}
+	return shutdownSeconds(app.config.ShutdownConfig.HookTimeout, 5)
+}
+
+// 停止钩子未指定超时时间时，使用所在步骤配置的超时时间
+func (app *App) stopHookTimeout(hook Hook) time.Duration {
+	if hook.Timeout > 0 {
+		return hook.Timeout
+	}
+	conf := app.config.ShutdownConfig
+	switch {
+	case hook.Order >= StopOrderHttp && hook.Order < StopOrderWorker:
+		return shutdownSeconds(conf.HttpTimeout, 10)
+	case hook.Order >= StopOrderWorker && hook.Order < StopOrderClient:
+		return shutdownSeconds(conf.WorkerTimeout, 10)
+	case hook.Order >= StopOrderClient && hook.Order < StopOrderLogger:
+		return shutdownSeconds(conf.ClientTimeout, 5)
+	default:
+		return app.hookTimeout(hook)
+	}
+}
+
+func shutdownSeconds(seconds, defaultSeconds int) time.Duration {
+	if seconds <= 0 {
+		seconds = defaultSeconds
+	}
+	return time.Second * time.Duration(seconds)
+}
+
+func sortHooks(hooks []Hook) []Hook {
+	sorted := make([]Hook, len(hooks))
+	copy(sorted, hooks)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Order < sorted[j].Order
+	})
+	return sorted
+}
+
+// 执行单个钩子，超时后不再等待钩子返回
+func runHook(ctx context.Context, hook Hook, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("hook %s panic: %v", hook.Name, r)
+			}
+		}()
+		done <- hook.Fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("hook %s: %w", hook.Name, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("hook %s: %w", hook.Name, ctx.Err())
+	}
+}
@@ -0,0 +1,141 @@
+package limiter
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Limiter 并发限制器
+type Limiter interface {
+	// Acquire 获取许可，成功时必须在请求结束后调用done上报耗时及是否失败，
+	// 请求未执行时latency传入负数，只释放许可不调整上限
+	Acquire() (done func(latency time.Duration, failed bool), ok bool)
+	// Limit 当前的并发上限
+	Limit() int
+	// Inflight 当前正在处理的请求数
+	Inflight() int
+}
+
+// fixed 固定并发上限
+type fixed struct {
+	limit    int64
+	inflight atomic.Int64
+}
+
+// NewFixed 创建固定并发上限的限制器
+func NewFixed(limit int) Limiter {
+	return &fixed{limit: int64(limit)}
+}
+
+func (l *fixed) Acquire() (func(time.Duration, bool), bool) {
+	if l.inflight.Add(1) > l.limit {
+		l.inflight.Add(-1)
+		return nil, false
+	}
+	return func(time.Duration, bool) { l.inflight.Add(-1) }, true
+}
+
+func (l *fixed) Limit() int {
+	return int(l.limit)
+}
+
+func (l *fixed) Inflight() int {
+	return int(l.inflight.Load())
+}
+
+// adaptive 根据请求结果动态调整并发上限，具体调整策略由update决定
+type adaptive struct {
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	min, max float64
+	update   func(limit float64, inflight int, latency time.Duration, failed bool) float64
+}
+
+func (l *adaptive) Acquire() (func(time.Duration, bool), bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inflight >= int(l.limit) {
+		return nil, false
+	}
+	l.inflight++
+	inflight := l.inflight
+	return func(latency time.Duration, failed bool) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.inflight--
+		if latency < 0 {
+			return
+		}
+		l.limit = math.Min(l.max, math.Max(l.min, l.update(l.limit, inflight, latency, failed)))
+	}, true
+}
+
+func (l *adaptive) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+func (l *adaptive) Inflight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inflight
+}
+
+// NewAIMD 创建加性增、乘性减的限制器：请求失败或耗时超过threshold时上限乘以0.9，
+// 并发接近上限且请求正常时上限加1，上限在[minLimit, maxLimit]之间变化，初始值为maxLimit
+func NewAIMD(minLimit, maxLimit int, threshold time.Duration) Limiter {
+	const backoff = 0.9
+	return &adaptive{
+		limit: float64(maxLimit),
+		min:   float64(minLimit),
+		max:   float64(maxLimit),
+		update: func(limit float64, inflight int, latency time.Duration, failed bool) float64 {
+			if failed || latency > threshold {
+				return limit * backoff
+			}
+			// 并发未达到上限一半时说明上限并非瓶颈，无需继续增加
+			if float64(inflight)*2 >= limit {
+				return limit + 1
+			}
+			return limit
+		},
+	}
+}
+
+// NewGradient 创建梯度限制器：以观察到的最小耗时作为无排队时的基准，
+// 按基准耗时与平滑后耗时的比值缩放上限，并预留sqrt(limit)的排队空间，上限在[minLimit, maxLimit]之间变化
+func NewGradient(minLimit, maxLimit int) Limiter {
+	const (
+		smoothing = 0.2  // 新上限的平滑系数
+		ewmaAlpha = 0.05 // 耗时指数加权平均的系数
+		minDecay  = 1.01 // 基准耗时缓慢上浮，避免网络抖动等导致的过低基准长期有效
+	)
+	var minLatency, avgLatency float64
+	return &adaptive{
+		limit: float64(maxLimit),
+		min:   float64(minLimit),
+		max:   float64(maxLimit),
+		update: func(limit float64, inflight int, latency time.Duration, failed bool) float64 {
+			sample := float64(latency)
+			if failed {
+				return limit * 0.9
+			}
+			if sample <= 0 {
+				return limit
+			}
+			if avgLatency == 0 {
+				avgLatency, minLatency = sample, sample
+			}
+			avgLatency = avgLatency*(1-ewmaAlpha) + sample*ewmaAlpha
+			minLatency = math.Min(minLatency*minDecay, sample)
+
+			gradient := math.Max(0.5, math.Min(1, minLatency/avgLatency))
+			newLimit := limit*gradient + math.Sqrt(limit)
+			return limit*(1-smoothing) + newLimit*smoothing
+		},
+	}
+}
@@ -0,0 +1,107 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+)
+
+// 占用n个许可后，最后获取的请求上报结果，其余只释放许可
+func complete(l Limiter, n int, latency time.Duration, failed bool) {
+	var dones []func(time.Duration, bool)
+	for i := 0; i < n; i++ {
+		if done, ok := l.Acquire(); ok {
+			dones = append(dones, done)
+		}
+	}
+	for i, done := range dones {
+		if i == len(dones)-1 {
+			done(latency, failed)
+		} else {
+			done(-1, false)
+		}
+	}
+}
+
+func TestFixedRejectsOverLimit(t *testing.T) {
+	l := NewFixed(1)
+	done, ok := l.Acquire()
+	if !ok {
+		t.Fatal("first Acquire() = false")
+	}
+	if _, ok := l.Acquire(); ok {
+		t.Fatal("Acquire() over limit = true")
+	}
+	done(time.Millisecond, false)
+	if _, ok := l.Acquire(); !ok {
+		t.Error("Acquire() after release = false")
+	}
+}
+
+func TestAIMDBacksOffOnSlowOrFailedRequests(t *testing.T) {
+	l := NewAIMD(10, 100, 100*time.Millisecond)
+	complete(l, 1, time.Second, false)
+	complete(l, 1, time.Millisecond, true)
+	if got := l.Limit(); got != 81 {
+		t.Errorf("Limit() = %d, want 81", got)
+	}
+	for i := 0; i < 100; i++ {
+		complete(l, 1, time.Second, false)
+	}
+	if got := l.Limit(); got != 10 {
+		t.Errorf("Limit() = %d, want min limit 10", got)
+	}
+}
+
+func TestAIMDGrowsOnlyWhenBusy(t *testing.T) {
+	l := NewAIMD(10, 100, 100*time.Millisecond)
+	complete(l, 1, time.Second, false)
+	complete(l, 1, time.Millisecond, false)
+	if got := l.Limit(); got != 90 {
+		t.Fatalf("Limit() with low inflight = %d, want 90", got)
+	}
+	complete(l, 60, time.Millisecond, false)
+	if got := l.Limit(); got != 91 {
+		t.Errorf("Limit() with high inflight = %d, want 91", got)
+	}
+}
+
+func TestAIMDIgnoresReleasedRequests(t *testing.T) {
+	l := NewAIMD(10, 100, 100*time.Millisecond)
+	complete(l, 1, -1, true)
+	if got := l.Limit(); got != 100 {
+		t.Errorf("Limit() = %d, want 100", got)
+	}
+	if got := l.Inflight(); got != 0 {
+		t.Errorf("Inflight() = %d, want 0", got)
+	}
+}
+
+func TestGradientShrinksWhenLatencyGrows(t *testing.T) {
+	l := NewGradient(10, 100)
+	for i := 0; i < 20; i++ {
+		complete(l, 1, 10*time.Millisecond, false)
+	}
+	if got := l.Limit(); got != 100 {
+		t.Fatalf("Limit() with steady latency = %d, want 100", got)
+	}
+	for i := 0; i < 200; i++ {
+		complete(l, 1, 200*time.Millisecond, false)
+	}
+	if got := l.Limit(); got >= 50 {
+		t.Errorf("Limit() after latency growth = %d, want < 50", got)
+	}
+}
+
+func TestGradientStopsAtMinLimit(t *testing.T) {
+	l := NewGradient(10, 100)
+	for i := 0; i < 20; i++ {
+		complete(l, 1, time.Millisecond, false)
+	}
+	// 基准耗时缓慢上浮，样本过多时上限会重新恢复
+	for i := 0; i < 300; i++ {
+		complete(l, 1, time.Second, false)
+	}
+	if got := l.Limit(); got != 10 {
+		t.Errorf("Limit() = %d, want min limit 10", got)
+	}
+}
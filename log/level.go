@@ -0,0 +1,119 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 日志名称，用于运行时调整日志级别
+const (
+	NameApp    = "app"
+	NameAccess = "access"
+	NameSql    = "sql"
+)
+
+// LevelStatus 日志级别状态
+type LevelStatus struct {
+	Name     string     `json:"name"`
+	Level    string     `json:"level"`
+	Base     string     `json:"base"`                // 配置的级别，临时调整到期后恢复为该级别
+	RevertAt *time.Time `json:"revert_at,omitempty"` // 临时调整的到期时间
+}
+
+type levelState struct {
+	level    zap.AtomicLevel
+	base     zapcore.Level
+	timer    *time.Timer
+	revertAt time.Time
+}
+
+var (
+	levelMu sync.Mutex
+	levels  = map[string]*levelState{
+		NameApp:    {level: zap.NewAtomicLevelAt(zapcore.InfoLevel), base: zapcore.InfoLevel},
+		NameAccess: {level: zap.NewAtomicLevelAt(zapcore.InfoLevel), base: zapcore.InfoLevel},
+		NameSql:    {level: zap.NewAtomicLevelAt(zapcore.InfoLevel), base: zapcore.InfoLevel},
+	}
+)
+
+// SetLevel 调整日志级别，name为空时调整全部日志。
+// revertAfter大于0时为临时调整，到期后恢复为配置的级别；否则作为新的配置级别
+func SetLevel(name, level string, revertAfter time.Duration) error {
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	var targets []*levelState
+	if name == "" {
+		for _, state := range levels {
+			targets = append(targets, state)
+		}
+	} else {
+		state, ok := levels[name]
+		if !ok {
+			return fmt.Errorf("unknown logger %q", name)
+		}
+		targets = append(targets, state)
+	}
+
+	for _, state := range targets {
+		state := state
+		if state.timer != nil {
+			state.timer.Stop()
+			state.timer, state.revertAt = nil, time.Time{}
+		}
+		state.level.SetLevel(lvl)
+		if revertAfter <= 0 {
+			state.base = lvl
+			continue
+		}
+		state.revertAt = time.Now().Add(revertAfter)
+		var timer *time.Timer
+		timer = time.AfterFunc(revertAfter, func() {
+			levelMu.Lock()
+			defer levelMu.Unlock()
+			// 到期前已被再次调整时不做处理
+			if state.timer != timer {
+				return
+			}
+			state.level.SetLevel(state.base)
+			state.timer, state.revertAt = nil, time.Time{}
+		})
+		state.timer = timer
+	}
+	return nil
+}
+
+// Levels 获取所有日志的级别状态，按名称排序
+func Levels() []LevelStatus {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	result := make([]LevelStatus, 0, len(levels))
+	for name, state := range levels {
+		status := LevelStatus{Name: name, Level: state.level.Level().String(), Base: state.base.String()}
+		if state.timer != nil {
+			revertAt := state.revertAt
+			status.RevertAt = &revertAt
+		}
+		result = append(result, status)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// 获取日志的级别，供创建日志时使用
+func atomicLevel(name string) zap.AtomicLevel {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	return levels[name].level
+}
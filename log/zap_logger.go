@@ -13,26 +13,35 @@ import (
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
+// 未调用InitLog前默认不输出任何日志
 var (
-	AccessLogger *zap.Logger
-	Logger       *zap.Logger
-	SqlLogger    *zap.Logger
+	AccessLogger = zap.NewNop()
+	Logger       = zap.NewNop()
+	SqlLogger    = zap.NewNop()
 
 	lumberJackLoggerDefault *lumberjack.Logger
 	lumberJackLoggerAccess  *lumberjack.Logger
 	lumberJackLoggerSql     *lumberjack.Logger
-)
 
-func init() {
-	InitLog(cfg.AppConf)
-}
+	levelUnsubscribe func()
+)
 
-// InitLog 配置日志模块
-func InitLog(cfg *cfg.AppConfig) {
-	var level zapcore.Level
-	if level.UnmarshalText([]byte(cfg.LogLevel)) != nil {
-		level = zapcore.InfoLevel
+// InitLog 配置日志模块，日志级别可以通过SetLevel在运行时调整，并随配置重新加载生效
+func InitLog(conf *cfg.AppConfig) {
+	// 配置的级别无效时使用info
+	if SetLevel("", conf.LogLevel, 0) != nil {
+		_ = SetLevel("", zapcore.InfoLevel.String(), 0)
 	}
+	// 配置重新加载后调整全部日志的级别，临时调整的级别同时失效
+	if levelUnsubscribe != nil {
+		levelUnsubscribe()
+	}
+	levelUnsubscribe = cfg.Subscribe(func(c *cfg.AppConfig) string { return c.LogLevel }, func(level string) {
+		if err := SetLevel("", level, 0); err == nil {
+			Logger.Info("Log level changed to " + level + " by config reload")
+		}
+	})
+	defaultLevel, accessLevel, sqlLevel := atomicLevel(NameApp), atomicLevel(NameAccess), atomicLevel(NameSql)
 
 	encoderConfig := zapcore.EncoderConfig{
 		LevelKey:       "level",
@@ -48,23 +57,23 @@ func InitLog(cfg *cfg.AppConfig) {
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
 
-	lumberJackLoggerDefault = newLunmberJackLogger(cfg.LogFile)
-	lumberJackLoggerAccess = newLunmberJackLogger(cfg.AccessLogFile)
-	lumberJackLoggerSql = newLunmberJackLogger(cfg.SqlLogFile)
+	lumberJackLoggerDefault = newLunmberJackLogger(conf.LogFile)
+	lumberJackLoggerAccess = newLunmberJackLogger(conf.AccessLogFile)
+	lumberJackLoggerSql = newLunmberJackLogger(conf.SqlLogFile)
 
 	var defaultCore, accessCore, sqlCore zapcore.Core
-	switch cfg.LogMode {
+	switch conf.LogMode {
 	case "console":
 		defaultCore = zapcore.NewTee(zapcore.NewCore(
-			zapcore.NewConsoleEncoder(encoderConfig), zapcore.AddSync(os.Stdout), level))
+			zapcore.NewConsoleEncoder(encoderConfig), zapcore.AddSync(os.Stdout), defaultLevel))
 		accessCore = zapcore.NewTee(zapcore.NewCore(
-			zapcore.NewConsoleEncoder(encoderConfig), zapcore.AddSync(os.Stdout), level))
+			zapcore.NewConsoleEncoder(encoderConfig), zapcore.AddSync(os.Stdout), accessLevel))
 		sqlCore = zapcore.NewTee(zapcore.NewCore(
-			zapcore.NewConsoleEncoder(encoderConfig), zapcore.AddSync(os.Stdout), level))
+			zapcore.NewConsoleEncoder(encoderConfig), zapcore.AddSync(os.Stdout), sqlLevel))
 	case "file":
-		defaultCore = newLoggerCore(lumberJackLoggerDefault, encoderConfig, level)
-		accessCore = newLoggerCore(lumberJackLoggerAccess, encoderConfig, level)
-		sqlCore = newLoggerCore(lumberJackLoggerSql, encoderConfig, level)
+		defaultCore = newLoggerCore(lumberJackLoggerDefault, encoderConfig, defaultLevel)
+		accessCore = newLoggerCore(lumberJackLoggerAccess, encoderConfig, accessLevel)
+		sqlCore = newLoggerCore(lumberJackLoggerSql, encoderConfig, sqlLevel)
 	}
 
 	Logger = zap.New(defaultCore, zap.AddCaller(), zap.AddCallerSkip(1))
@@ -72,7 +81,7 @@ func InitLog(cfg *cfg.AppConfig) {
 	SqlLogger = zap.New(sqlCore, zap.AddCaller(), zap.AddCallerSkip(1))
 }
 
-func newLoggerCore(logger *lumberjack.Logger, encoderConfig zapcore.EncoderConfig, level zapcore.Level) zapcore.Core {
+func newLoggerCore(logger *lumberjack.Logger, encoderConfig zapcore.EncoderConfig, level zapcore.LevelEnabler) zapcore.Core {
 	writer := zapcore.AddSync(logger)
 	return zapcore.NewTee(zapcore.NewCore(
 		zapcore.NewJSONEncoder(encoderConfig), zapcore.AddSync(writer), level))
@@ -133,6 +142,9 @@ func getTraceId(ctx context.Context) string {
 }
 
 func Close() {
+	if lumberJackLoggerDefault == nil {
+		return
+	}
 	_ = lumberJackLoggerDefault.Rotate()
 	_ = lumberJackLoggerAccess.Rotate()
 	_ = lumberJackLoggerSql.Rotate()
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPoolCollector redis连接池指标
+type redisPoolCollector struct {
+	stats func() map[string]*redis.PoolStats
+
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	timeouts   *prometheus.Desc
+	totalConns *prometheus.Desc
+	idleConns  *prometheus.Desc
+	staleConns *prometheus.Desc
+}
+
+// NewRedisPoolCollector 创建redis连接池指标，stats返回客户端名称到连接池状态的映射
+func NewRedisPoolCollector(stats func() map[string]*redis.PoolStats) prometheus.Collector {
+	newDesc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis_pool", name), help, []string{"client"}, nil)
+	}
+	return &redisPoolCollector{
+		stats:      stats,
+		hits:       newDesc("hits_total", "Number of times a free connection was found in the pool."),
+		misses:     newDesc("misses_total", "Number of times a free connection was not found in the pool."),
+		timeouts:   newDesc("timeouts_total", "Number of times a wait timeout occurred."),
+		totalConns: newDesc("total_conns", "Number of total connections in the pool."),
+		idleConns:  newDesc("idle_conns", "Number of idle connections in the pool."),
+		staleConns: newDesc("stale_conns_total", "Number of stale connections removed from the pool."),
+	}
+}
+
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.totalConns
+	ch <- c.idleConns
+	ch <- c.staleConns
+}
+
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	for client, stats := range c.stats() {
+		ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits), client)
+		ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses), client)
+		ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats.Timeouts), client)
+		ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stats.TotalConns), client)
+		ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stats.IdleConns), client)
+		ch <- prometheus.MustNewConstMetric(c.staleConns, prometheus.CounterValue, float64(stats.StaleConns), client)
+	}
+}
+
+// goPoolCollector 协程池指标
+type goPoolCollector struct {
+	stats func() (running, free, capacity int)
+
+	running  *prometheus.Desc
+	free     *prometheus.Desc
+	capacity *prometheus.Desc
+}
+
+// NewGoPoolCollector 创建协程池指标
+func NewGoPoolCollector(stats func() (running, free, capacity int)) prometheus.Collector {
+	return &goPoolCollector{
+		stats:    stats,
+		running:  prometheus.NewDesc(prometheus.BuildFQName(namespace, "go_pool", "running"), "Number of running goroutines in the pool.", nil, nil),
+		free:     prometheus.NewDesc(prometheus.BuildFQName(namespace, "go_pool", "free"), "Number of available goroutines in the pool.", nil, nil),
+		capacity: prometheus.NewDesc(prometheus.BuildFQName(namespace, "go_pool", "capacity"), "Capacity of the pool.", nil, nil),
+	}
+}
+
+func (c *goPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.running
+	ch <- c.free
+	ch <- c.capacity
+}
+
+func (c *goPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	running, free, capacity := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.running, prometheus.GaugeValue, float64(running))
+	ch <- prometheus.MustNewConstMetric(c.free, prometheus.GaugeValue, float64(free))
+	ch <- prometheus.MustNewConstMetric(c.capacity, prometheus.GaugeValue, float64(capacity))
+}
+
+// dbStatsCollector 数据库连接池指标，连接建立前不输出
+type dbStatsCollector struct {
+	name     string
+	resolved func() (*sql.DB, bool)
+	desc     prometheus.Collector // 只用于输出指标描述
+}
+
+// NewDBStatsCollector 创建数据库连接池指标，resolved返回已建立的连接，连接采用延迟创建时不会因采集指标而提前连接
+func NewDBStatsCollector(name string, resolved func() (*sql.DB, bool)) prometheus.Collector {
+	return &dbStatsCollector{
+		name:     name,
+		resolved: resolved,
+		desc:     collectors.NewDBStatsCollector(nil, name),
+	}
+}
+
+func (c *dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.desc.Describe(ch)
+}
+
+func (c *dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	// 每次采集时获取当前的连接，连接重新建立后不会采集到已关闭的连接池
+	sqlDB, ok := c.resolved()
+	if !ok {
+		return
+	}
+	collectors.NewDBStatsCollector(sqlDB, c.name).Collect(ch)
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func maxOpenConnections(t *testing.T, collector prometheus.Collector) float64 {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 32)
+	collector.Collect(ch)
+	close(ch)
+	for metric := range ch {
+		if !strings.Contains(metric.Desc().String(), "go_sql_max_open_connections") {
+			continue
+		}
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetGauge().GetValue()
+	}
+	t.Fatal("go_sql_max_open_connections not collected")
+	return 0
+}
+
+// 连接重新建立后采集新的连接池
+func TestDBStatsCollectorFollowsReopenedDB(t *testing.T) {
+	open := func(maxOpen int) *sql.DB {
+		db, err := sql.Open("mysql", "user:pass@tcp(127.0.0.1:3306)/app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.SetMaxOpenConns(maxOpen)
+		t.Cleanup(func() { _ = db.Close() })
+		return db
+	}
+	current := open(1)
+	collector := NewDBStatsCollector("default", func() (*sql.DB, bool) { return current, true })
+	if got := maxOpenConnections(t, collector); got != 1 {
+		t.Fatalf("max open connections = %v, want 1", got)
+	}
+	current = open(7)
+	if got := maxOpenConnections(t, collector); got != 7 {
+		t.Errorf("max open connections after reopen = %v, want 7", got)
+	}
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/easonchen147/foundation/buildinfo"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "foundation"
+
+// Registry 应用全局的指标注册中心
+var Registry = newRegistry()
+
+var (
+	// HttpRequestsTotal http请求总数
+	HttpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of http requests.",
+	}, []string{"method", "route", "code"})
+
+	// HttpRequestDuration http请求耗时
+	HttpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of http requests in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// HttpRequestsInflight 正在处理的http请求数
+	HttpRequestsInflight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_inflight",
+		Help:      "Number of http requests currently being served.",
+	}, []string{"method", "route"})
+
+	// PanicsTotal http请求处理过程中发生的panic次数
+	PanicsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_panics_total",
+		Help:      "Total number of panics recovered while serving http requests.",
+	}, []string{"method", "route"})
+
+	// HttpRequestsShed 因并发超限被拒绝的http请求数，scope为global或路由模板
+	HttpRequestsShed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_shed_total",
+		Help:      "Total number of http requests rejected by the concurrency limiter.",
+	}, []string{"scope"})
+
+	// HttpConcurrencyLimit 当前的并发上限，scope为global或路由模板
+	HttpConcurrencyLimit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_concurrency_limit",
+		Help:      "Current concurrency limit of http requests.",
+	}, []string{"scope"})
+
+	// RealtimeConnections 当前的WebSocket、SSE连接数
+	RealtimeConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "realtime_connections",
+		Help:      "Number of open realtime connections.",
+	}, []string{"transport"})
+
+	// RealtimeDropped 因发送队列已满被断开的连接数
+	RealtimeDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "realtime_slow_consumers_total",
+		Help:      "Total number of realtime connections closed because their send queue was full.",
+	}, []string{"transport"})
+)
+
+// 带有构建版本常量标签的注册器，通过它注册的指标都会附带build_version与build_commit标签
+var registerer prometheus.Registerer
+
+func newRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	info := buildinfo.Get()
+	registerer = prometheus.WrapRegistererWith(prometheus.Labels{
+		"build_version": info.Version,
+		"build_commit":  info.ShortCommit(),
+	}, registry)
+	registerer.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HttpRequestsTotal,
+		HttpRequestDuration,
+		HttpRequestsInflight,
+		PanicsTotal,
+		HttpRequestsShed,
+		HttpConcurrencyLimit,
+		RealtimeConnections,
+		RealtimeDropped,
+	)
+	return registry
+}
+
+// Register 注册指标，已存在相同指标时进行替换
+func Register(collector prometheus.Collector) error {
+	registerer.Unregister(collector)
+	return registerer.Register(collector)
+}
+
+// Handler 以prometheus文本格式输出指标
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth 管理接口访问控制，allowIps支持ip或cidr，user为空时不校验basic auth
+func AdminAuth(user, pass string, allowIps []string) gin.HandlerFunc {
+	var nets []*net.IPNet
+	var ips []net.IP
+	for _, allowIp := range allowIps {
+		if _, ipNet, err := net.ParseCIDR(allowIp); err == nil {
+			nets = append(nets, ipNet)
+		} else if ip := net.ParseIP(allowIp); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	restrictIp := len(allowIps) > 0
+
+	return func(c *gin.Context) {
+		if restrictIp && !ipAllowed(net.ParseIP(c.RemoteIP()), ips, nets) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		if user != "" {
+			reqUser, reqPass, ok := c.Request.BasicAuth()
+			if !ok || subtle.ConstantTimeCompare([]byte(reqUser), []byte(user)) != 1 ||
+				subtle.ConstantTimeCompare([]byte(reqPass), []byte(pass)) != 1 {
+				c.Header("WWW-Authenticate", `Basic realm="admin"`)
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+func ipAllowed(ip net.IP, ips []net.IP, nets []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, allowed := range ips {
+		if allowed.Equal(ip) {
+			return true
+		}
+	}
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"github.com/easonchen147/foundation/constant"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientIdentity 已校验的客户端证书身份
+type ClientIdentity struct {
+	CommonName   string   `json:"commonName"`
+	Organization []string `json:"organization"`
+	DNSNames     []string `json:"dnsNames"`
+	URIs         []string `json:"uris"`
+	SerialNumber string   `json:"serialNumber"`
+	Issuer       string   `json:"issuer"`
+}
+
+// ClientCert 将双向认证中已校验的客户端证书身份写入上下文
+func ClientCert() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := c.Request.TLS
+		if state != nil && len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 0 {
+			cert := state.VerifiedChains[0][0]
+			uris := make([]string, 0, len(cert.URIs))
+			for _, uri := range cert.URIs {
+				uris = append(uris, uri.String())
+			}
+			c.Set(constant.ClientIdentityKey, &ClientIdentity{
+				CommonName:   cert.Subject.CommonName,
+				Organization: cert.Subject.Organization,
+				DNSNames:     cert.DNSNames,
+				URIs:         uris,
+				SerialNumber: cert.SerialNumber.String(),
+				Issuer:       cert.Issuer.String(),
+			})
+		}
+		c.Next()
+	}
+}
+
+// GetClientIdentity 获取已校验的客户端证书身份，未启用双向认证时返回false
+func GetClientIdentity(c *gin.Context) (*ClientIdentity, bool) {
+	obj, ok := c.Get(constant.ClientIdentityKey)
+	if !ok {
+		return nil, false
+	}
+	identity, ok := obj.(*ClientIdentity)
+	return identity, ok
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/easonchen147/foundation/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics 记录请求数、耗时及正在处理的请求数，按路由模板聚合
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" { // 未匹配到路由的请求统一归类，避免指标维度膨胀
+			route = "unmatched"
+		}
+		method := c.Request.Method
+
+		inflight := metrics.HttpRequestsInflight.WithLabelValues(method, route)
+		inflight.Inc()
+		startTime := time.Now()
+
+		c.Next()
+
+		inflight.Dec()
+		metrics.HttpRequestDuration.WithLabelValues(method, route).Observe(time.Since(startTime).Seconds())
+		metrics.HttpRequestsTotal.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/easonchen147/foundation/log"
+	"github.com/easonchen147/foundation/metrics"
+	"github.com/easonchen147/foundation/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 日志中记录的请求体默认最大长度
+const defaultMaxBodySize = 1024
+
+// RecoveryConfig panic恢复配置
+type RecoveryConfig struct {
+	Dev         bool // 开发环境在响应中返回panic信息及堆栈
+	MaxBodySize int  // 日志中记录的请求体最大长度，默认1024字节
+	// Handler 自定义panic后的响应，为nil时返回response.ErrInternal
+	Handler func(c *gin.Context, err interface{})
+}
+
+// Recovery 捕获panic，记录堆栈、请求信息并返回统一的错误响应，客户端断开连接导致的错误不视为服务异常
+func Recovery(conf RecoveryConfig) gin.HandlerFunc {
+	if conf.MaxBodySize <= 0 {
+		conf.MaxBodySize = defaultMaxBodySize
+	}
+	return func(c *gin.Context) {
+		var recorder *bodyRecorder
+		if c.Request.Body != nil {
+			recorder = &bodyRecorder{ReadCloser: c.Request.Body, limit: conf.MaxBodySize}
+			c.Request.Body = recorder
+		}
+
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if isBrokenPipe(err) {
+				log.Warn(c, "connection broken, method: %s, path: %s, error: %v", c.Request.Method, c.Request.URL.Path, err)
+				if e, ok := err.(error); ok {
+					_ = c.Error(e)
+				}
+				c.Abort()
+				return
+			}
+
+			stack := debug.Stack()
+			route := c.FullPath()
+			log.Error(c, "panic recovery: %v, method: %s, route: %s, path: %s, body: %s\n%s",
+				err, c.Request.Method, route, c.Request.URL.Path, recorder.body(), stack)
+			metrics.PanicsTotal.WithLabelValues(c.Request.Method, route).Inc()
+
+			switch {
+			case conf.Handler != nil:
+				conf.Handler(c, err)
+			case conf.Dev:
+				response.FailWithData(c, response.ErrInternal, gin.H{
+					"panic": fmt.Sprint(err),
+					"stack": strings.Split(string(stack), "\n"),
+				})
+			default:
+				response.Fail(c, response.ErrInternal)
+			}
+		}()
+		c.Next()
+	}
+}
+
+// 判断是否为客户端断开连接导致的错误
+func isBrokenPipe(err interface{}) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	if !errors.As(e, &opErr) {
+		return false
+	}
+	var syscallErr *os.SyscallError
+	if !errors.As(opErr, &syscallErr) {
+		return false
+	}
+	msg := strings.ToLower(syscallErr.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
+
+// bodyRecorder 记录处理过程中读取的请求体前limit个字节
+type bodyRecorder struct {
+	io.ReadCloser
+	limit int
+	buf   bytes.Buffer
+}
+
+func (r *bodyRecorder) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if remain := r.limit - r.buf.Len(); remain > 0 && n > 0 {
+		if n < remain {
+			remain = n
+		}
+		r.buf.Write(p[:remain])
+	}
+	return n, err
+}
+
+// body 获取已记录的请求体，处理过程中未读取请求体时尝试读取剩余内容
+func (r *bodyRecorder) body() string {
+	if r == nil {
+		return ""
+	}
+	if r.buf.Len() == 0 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(r, int64(r.limit)))
+	}
+	return r.buf.String()
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/easonchen147/foundation/cfg"
+	"github.com/easonchen147/foundation/limiter"
+	"github.com/easonchen147/foundation/metrics"
+	"github.com/easonchen147/foundation/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const scopeGlobal = "global"
+
+// 不参与限流的路由模板
+var shedExempt sync.Map
+
+// ExemptShed 指定不参与限流的路由模板(gin的FullPath)，用于websocket、sse等长连接接口，
+// 避免长期占用并发数，且连接时长不会被当作延迟影响自适应上限。请求头由客户端控制，不作为豁免依据
+func ExemptShed(routes ...string) {
+	for _, route := range routes {
+		shedExempt.Store(route, struct{}{})
+	}
+}
+
+// Shed 并发请求数超过全局或路由上限时直接返回503及Retry-After，避免请求堆积拖垮服务，ExemptShed指定的路由不参与限流
+func Shed(cfg *cfg.AppConfig) gin.HandlerFunc {
+	conf := cfg.LimiterConfig
+	var global limiter.Limiter
+	if conf.MaxInflight > 0 {
+		global = newLimiter(conf.Adaptive, conf.MinLimit, conf.MaxInflight, conf.LatencyThreshold)
+		metrics.HttpConcurrencyLimit.WithLabelValues(scopeGlobal).Set(float64(global.Limit()))
+	}
+	routes := make(map[string]limiter.Limiter, len(conf.RouteMaxInflight))
+	for route, limit := range conf.RouteMaxInflight {
+		if limit > 0 {
+			routes[route] = newLimiter(conf.Adaptive, conf.MinLimit, limit, conf.LatencyThreshold)
+			metrics.HttpConcurrencyLimit.WithLabelValues(route).Set(float64(limit))
+		}
+	}
+	retryAfter := conf.RetryAfter
+	if retryAfter <= 0 {
+		retryAfter = 1
+	}
+
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if _, ok := shedExempt.Load(route); ok && route != "" {
+			c.Next()
+			return
+		}
+		var routeDone, globalDone func(time.Duration, bool)
+		if l, ok := routes[route]; ok {
+			if routeDone, ok = l.Acquire(); !ok {
+				reject(c, route, retryAfter)
+				return
+			}
+		}
+		if global != nil {
+			var ok bool
+			if globalDone, ok = global.Acquire(); !ok {
+				if routeDone != nil {
+					routeDone(-1, false)
+				}
+				reject(c, scopeGlobal, retryAfter)
+				return
+			}
+		}
+
+		startTime := time.Now()
+		defer func() {
+			latency := time.Since(startTime)
+			failed := c.Writer.Status() >= 500
+			if routeDone != nil {
+				routeDone(latency, failed)
+				metrics.HttpConcurrencyLimit.WithLabelValues(route).Set(float64(routes[route].Limit()))
+			}
+			if globalDone != nil {
+				globalDone(latency, failed)
+				metrics.HttpConcurrencyLimit.WithLabelValues(scopeGlobal).Set(float64(global.Limit()))
+			}
+		}()
+		c.Next()
+	}
+}
+
+func reject(c *gin.Context, scope string, retryAfter int) {
+	metrics.HttpRequestsShed.WithLabelValues(scope).Inc()
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	response.Fail(c, response.ErrServiceUnavailable)
+}
+
+// 根据配置创建限制器，自适应算法的上限不超过配置的最大并发数
+func newLimiter(adaptive string, minLimit, maxLimit, latencyThreshold int) limiter.Limiter {
+	if minLimit <= 0 {
+		minLimit = 10
+	}
+	if minLimit > maxLimit {
+		minLimit = maxLimit
+	}
+	if latencyThreshold <= 0 {
+		latencyThreshold = 1000
+	}
+	switch adaptive {
+	case "aimd":
+		return limiter.NewAIMD(minLimit, maxLimit, time.Millisecond*time.Duration(latencyThreshold))
+	case "gradient":
+		return limiter.NewGradient(minLimit, maxLimit)
+	default:
+		return limiter.NewFixed(maxLimit)
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/easonchen147/foundation/cfg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 在/hold请求处理期间请求/ping，返回/ping的状态码
+func pingWhileHolding(t *testing.T, holdHeader http.Header) int {
+	return requestWhileHolding(t, "/hold", holdHeader, "/ping")
+}
+
+// 在hold请求处理期间请求path，返回path的状态码
+func requestWhileHolding(t *testing.T, holdPath string, holdHeader http.Header, path string) int {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	config, err := cfg.LoadConfigMap(map[string]interface{}{"limiter.max_inflight": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entered, release := make(chan struct{}), make(chan struct{})
+	router := gin.New()
+	router.Use(Shed(config))
+	holdHandler := func(c *gin.Context) {
+		close(entered)
+		<-release
+	}
+	router.GET("/hold", holdHandler)
+	router.GET("/stream", holdHandler)
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	hold := httptest.NewRequest(http.MethodGet, holdPath, nil)
+	hold.Header = holdHeader
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		router.ServeHTTP(httptest.NewRecorder(), hold)
+	}()
+	<-entered
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	close(release)
+	<-done
+	return w.Code
+}
+
+func TestShedRejectsOverLimit(t *testing.T) {
+	if got := pingWhileHolding(t, http.Header{}); got != http.StatusServiceUnavailable {
+		t.Errorf("GET /ping = %d, want 503", got)
+	}
+}
+
+// 普通路由即使带有长连接的请求头也参与限流
+func TestShedIgnoresLongLivedHeaders(t *testing.T) {
+	header := http.Header{
+		"Accept":     {"text/event-stream"},
+		"Connection": {"keep-alive, Upgrade"},
+		"Upgrade":    {"websocket"},
+	}
+	if got := pingWhileHolding(t, header); got != http.StatusServiceUnavailable {
+		t.Errorf("GET /ping = %d, want 503", got)
+	}
+}
+
+// 豁免的长连接不占用并发数
+func TestShedExemptRouteHoldsNoSlot(t *testing.T) {
+	ExemptShed("/stream")
+	t.Cleanup(func() { shedExempt.Delete("/stream") })
+	if got := requestWhileHolding(t, "/stream", http.Header{}, "/ping"); got != http.StatusOK {
+		t.Errorf("GET /ping = %d, want 200", got)
+	}
+}
+
+// 豁免的路由在并发超限时仍然可以访问
+func TestShedSkipsExemptRoute(t *testing.T) {
+	ExemptShed("/ping")
+	t.Cleanup(func() { shedExempt.Delete("/ping") })
+	if got := pingWhileHolding(t, http.Header{}); got != http.StatusOK {
+		t.Errorf("GET /ping = %d, want 200", got)
+	}
+}
@@ -6,9 +6,10 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"io"
-	"net/http"
+	"sync/atomic"
 
 	"github.com/easonchen147/foundation/cfg"
+	"github.com/easonchen147/foundation/response"
 
 	"github.com/gin-gonic/gin"
 )
@@ -45,32 +46,48 @@ func (b *BodySigner) verify(c *gin.Context) (bool, error) {
 	return origin == current, nil
 }
 
-var bodySigner *BodySigner
+var (
+	bodySigner      atomic.Pointer[BodySigner]
+	signUnsubscribe func()
+)
+
+// InitSign 初始化请求体签名校验，配置重新加载后新的秘钥立即生效
+func InitSign(conf *cfg.AppConfig) {
+	bodySigner.Store(newBodySigner(conf))
+	if signUnsubscribe != nil {
+		signUnsubscribe()
+	}
+	signUnsubscribe = cfg.Subscribe(newBodySigner, func(signer *BodySigner) {
+		bodySigner.Store(signer)
+	})
+}
 
-func init() {
-	if cfg.AppConf.SignConfig == nil || cfg.AppConf.SignConfig.Secret == "" {
-		return
+// 未配置秘钥时返回nil
+func newBodySigner(conf *cfg.AppConfig) *BodySigner {
+	if conf.SignConfig == nil || conf.SignConfig.Secret == "" {
+		return nil
 	}
-	bodySigner = &BodySigner{
-		secret: []byte(cfg.AppConf.SignConfig.Secret),
-		salt:   []byte(cfg.AppConf.SignConfig.Salt),
+	return &BodySigner{
+		secret: []byte(conf.SignConfig.Secret),
+		salt:   []byte(conf.SignConfig.Salt),
 	}
 }
 
 func VerifySk() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if bodySigner == nil { //未配置签名校验秘钥的直接跳过
+		signer := bodySigner.Load()
+		if signer == nil { //未配置签名校验秘钥的直接跳过
 			c.Next()
 			return
 		}
 
-		match, err := bodySigner.verify(c)
+		match, err := signer.verify(c)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, nil)
+			response.Fail(c, response.ErrBadRequest)
 			return
 		}
 		if !match {
-			c.JSON(http.StatusBadRequest, nil)
+			response.Fail(c, response.ErrInvalidSign)
 			return
 		}
 	}
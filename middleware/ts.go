@@ -1,11 +1,12 @@
 package middleware
 
 import (
-	"net/http"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/easonchen147/foundation/cfg"
+	"github.com/easonchen147/foundation/response"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,39 +25,55 @@ func (b *TsVerify) verify(c *gin.Context) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	originTime := time.Unix(second, 0)
-	return time.Since(originTime) > b.Expire, nil
+	// 客户端时间可能快于服务端，按绝对偏差校验，避免未来时间戳绕过过期校验
+	skew := time.Since(time.Unix(second, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	return skew <= b.Expire, nil
 }
 
-var tsVerifier *TsVerify
+var (
+	tsVerifier    atomic.Pointer[TsVerify]
+	tsUnsubscribe func()
+)
 
-func init() {
-	if cfg.AppConf.TsConfig == nil || cfg.AppConf.TsConfig.Expire == "" {
-		return
+// InitTs 初始化请求时间戳校验，配置重新加载后ts.expire立即生效
+func InitTs(conf *cfg.AppConfig) {
+	tsVerifier.Store(newTsVerify(conf))
+	if tsUnsubscribe != nil {
+		tsUnsubscribe()
 	}
-	expire, err := time.ParseDuration(cfg.AppConf.TsConfig.Expire)
+	tsUnsubscribe = cfg.Subscribe(newTsVerify, func(verifier *TsVerify) {
+		tsVerifier.Store(verifier)
+	})
+}
+
+// 未配置或配置无效时返回nil，无效的配置由cfg.Validate校验
+func newTsVerify(conf *cfg.AppConfig) *TsVerify {
+	if conf.TsConfig == nil || conf.TsConfig.Expire == "" {
+		return nil
+	}
+	expire, err := time.ParseDuration(conf.TsConfig.Expire)
 	if err != nil {
-		return
+		return nil
 	}
-	tsVerifier = &TsVerify{
+	return &TsVerify{
 		Expire: expire,
 	}
 }
 
 func VerifyTs() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if tsVerifier == nil { //未配置接口请求时间戳校验的直接跳过
+		verifier := tsVerifier.Load()
+		if verifier == nil { //未配置接口请求时间戳校验的直接跳过
 			c.Next()
 			return
 		}
 
-		ok, err := tsVerifier.verify(c)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, nil)
-			return
-		}
-		if !ok {
-			c.JSON(http.StatusBadRequest, nil)
+		ok, err := verifier.verify(c)
+		if err != nil || !ok {
+			response.Fail(c, response.ErrInvalidTs)
 			return
 		}
 	}
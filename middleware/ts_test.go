@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 以指定的时间戳请求，返回请求是否通过校验
+func tsAccepted(t *testing.T, ts time.Time) bool {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Header.Set(HeaderTsKey, strconv.FormatInt(ts.Unix(), 10))
+	ok, err := (&TsVerify{Expire: time.Minute}).verify(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ok
+}
+
+func TestTsAcceptsValid(t *testing.T) {
+	if !tsAccepted(t, time.Now().Add(-30*time.Second)) {
+		t.Error("timestamp within expire rejected")
+	}
+}
+
+func TestTsRejectsExpired(t *testing.T) {
+	if tsAccepted(t, time.Now().Add(-2*time.Minute)) {
+		t.Error("expired timestamp accepted")
+	}
+}
+
+func TestTsRejectsFuture(t *testing.T) {
+	if tsAccepted(t, time.Now().Add(2*time.Minute)) {
+		t.Error("future timestamp beyond expire accepted")
+	}
+}
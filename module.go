@@ -0,0 +1,146 @@
+package foundation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/easonchen147/foundation/cfg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Module 可插拔的功能模块，按依赖顺序初始化并注册路由，应用停止时按相反顺序关闭
+type Module interface {
+	Name() string
+	// Init 初始化模块，依赖的模块与组件已完成初始化
+	Init(ctx context.Context, deps *Deps) error
+	// RegisterRoutes 注册模块路由
+	RegisterRoutes(router *gin.RouterGroup)
+	// Close 释放模块资源，在http请求排空、后台任务停止之后，数据客户端关闭之前执行
+	Close(ctx context.Context) error
+}
+
+// Requirements 模块的依赖
+type Requirements struct {
+	Modules    []string    // 依赖的模块名称
+	Components []Component // 依赖的基础组件，未通过WithComponents指定时自动启用
+}
+
+// Requirer 模块可选实现的接口，用于声明依赖
+type Requirer interface {
+	Requires() Requirements
+}
+
+// Deps 模块初始化时可以使用的依赖
+type Deps struct {
+	App    *App
+	Config *cfg.AppConfig
+
+	modules map[string]Module
+}
+
+// Module 获取已初始化的模块，未初始化时返回nil
+func (d *Deps) Module(name string) Module {
+	return d.modules[name]
+}
+
+func requirements(module Module) Requirements {
+	if requirer, ok := module.(Requirer); ok {
+		return requirer.Requires()
+	}
+	return Requirements{}
+}
+
+// 按依赖关系对模块排序，被依赖的模块在前，无依赖关系的模块保持注册顺序
+func sortModules(modules []Module) ([]Module, error) {
+	byName := make(map[string]Module, len(modules))
+	for _, module := range modules {
+		if _, ok := byName[module.Name()]; ok {
+			return nil, fmt.Errorf("module %s registered more than once", module.Name())
+		}
+		byName[module.Name()] = module
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(modules))
+	sorted := make([]Module, 0, len(modules))
+	var visit func(module Module, path []string) error
+	visit = func(module Module, path []string) error {
+		name := module.Name()
+		path = append(path, name)
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("module dependency cycle: %s", strings.Join(path, " -> "))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range requirements(module).Modules {
+			depModule, ok := byName[dep]
+			if !ok {
+				return fmt.Errorf("module %s depends on unregistered module %s", name, dep)
+			}
+			if err := visit(depModule, path); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		sorted = append(sorted, module)
+		return nil
+	}
+	for _, module := range modules {
+		if err := visit(module, nil); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// 合并模块依赖的组件，保持已指定组件的顺序
+func mergeComponents(components []Component, modules []Module) []Component {
+	exists := make(map[Component]bool, len(components))
+	for _, component := range components {
+		exists[component] = true
+	}
+	for _, module := range modules {
+		for _, component := range requirements(module).Components {
+			if !exists[component] {
+				exists[component] = true
+				components = append(components, component)
+			}
+		}
+	}
+	return components
+}
+
+// 按顺序初始化模块并注册路由，失败时按相反顺序关闭已初始化的模块
+func (app *App) initModules(modules []Module) error {
+	deps := &Deps{App: app, Config: app.config, modules: make(map[string]Module, len(modules))}
+	for i, module := range modules {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownSeconds(app.config.ShutdownConfig.HookTimeout, 5))
+		err := module.Init(ctx, deps)
+		cancel()
+		if err != nil {
+			errs := []error{fmt.Errorf("init module %s failed: %w", module.Name(), err)}
+			for j := i - 1; j >= 0; j-- {
+				ctx, cancel := context.WithTimeout(context.Background(), shutdownSeconds(app.config.ShutdownConfig.HookTimeout, 5))
+				if err := modules[j].Close(ctx); err != nil {
+					errs = append(errs, fmt.Errorf("close module %s failed: %w", modules[j].Name(), err))
+				}
+				cancel()
+			}
+			return errors.Join(errs...)
+		}
+		deps.modules[module.Name()] = module
+	}
+	for _, module := range modules {
+		module.RegisterRoutes(&app.engine.RouterGroup)
+	}
+	app.modules = modules
+	return nil
+}
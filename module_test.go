@@ -0,0 +1,69 @@
+package foundation
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testModule struct {
+	name       string
+	modules    []string
+	components []Component
+}
+
+func (m *testModule) Name() string                      { return m.name }
+func (m *testModule) Init(context.Context, *Deps) error { return nil }
+func (m *testModule) RegisterRoutes(*gin.RouterGroup)   {}
+func (m *testModule) Close(context.Context) error       { return nil }
+func (m *testModule) Requires() Requirements {
+	return Requirements{Modules: m.modules, Components: m.components}
+}
+
+func TestSortModulesPutsDependenciesFirst(t *testing.T) {
+	sorted, err := sortModules([]Module{
+		&testModule{name: "order", modules: []string{"user"}},
+		&testModule{name: "audit"},
+		&testModule{name: "user", modules: []string{"auth"}},
+		&testModule{name: "auth"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, module := range sorted {
+		names = append(names, module.Name())
+	}
+	if want := []string{"auth", "user", "order", "audit"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("sortModules() = %v, want %v", names, want)
+	}
+}
+
+func TestSortModulesReportsCycle(t *testing.T) {
+	_, err := sortModules([]Module{
+		&testModule{name: "a", modules: []string{"b"}},
+		&testModule{name: "b", modules: []string{"a"}},
+	})
+	if err == nil || err.Error() != "module dependency cycle: a -> b -> a" {
+		t.Errorf("sortModules() error = %v, want cycle a -> b -> a", err)
+	}
+}
+
+func TestSortModulesReportsMissingDependency(t *testing.T) {
+	_, err := sortModules([]Module{&testModule{name: "order", modules: []string{"user"}}})
+	if err == nil || err.Error() != "module order depends on unregistered module user" {
+		t.Errorf("sortModules() error = %v, want missing module user", err)
+	}
+}
+
+func TestMergeComponents(t *testing.T) {
+	got := mergeComponents([]Component{ComponentMysql}, []Module{
+		&testModule{name: "a", components: []Component{ComponentRedis, ComponentMysql}},
+		&testModule{name: "b", components: []Component{ComponentKafka}},
+	})
+	if want := []Component{ComponentMysql, ComponentRedis, ComponentKafka}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeComponents() = %v, want %v", got, want)
+	}
+}
@@ -2,66 +2,74 @@ package mongo
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"time"
 
 	"github.com/easonchen147/foundation/cfg"
+	"github.com/easonchen147/foundation/container"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
+// 未配置connect_timeout时建立连接的超时时间
+const defaultConnectTimeout = 5 * time.Second
+
 type Mgo struct {
 	Client *mongo.Client
 	Db     *mongo.Database
 }
 
-var (
-	mgo *Mgo
-)
-
-func init() {
-	err := InitMongo(cfg.AppConf)
-	if err != nil {
-		panic(fmt.Sprintf("init mongo failed: %s", err))
-	}
-}
-
+// InitMongo 注册mongo，在首次使用时建立连接
 func InitMongo(cfg *cfg.AppConfig) error {
 	if cfg.MongoConfig == nil {
 		return nil
 	}
-	var err error
-	mgo, err = connectMongo(cfg)
-	if err != nil {
-		return err
-	}
+	container.Provide(container.Default, "", func() (*Mgo, error) {
+		return connectMongo(cfg)
+	})
 	return nil
 }
 
+// Mongo 获取mongo客户端，连接失败时panic，需要处理错误时使用Get
 func Mongo() *Mgo {
-	if mgo == nil {
-		panic(errors.New("mongodb is not ready"))
+	mgo, err := Get()
+	if err != nil {
+		panic(fmt.Errorf("mongodb is not ready: %w", err))
 	}
 	return mgo
 }
 
+// Get 获取mongo客户端，首次获取时建立连接
+func Get() (*Mgo, error) {
+	return container.Resolve[*Mgo](container.Default, "")
+}
+
+// 建立连接并ping，整体耗时不超过connect_timeout
 func connectMongo(cfg *cfg.AppConfig) (*Mgo, error) {
+	timeout := time.Duration(cfg.MongoConfig.ConnectTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultConnectTimeout
+	}
 	option := options.Client().ApplyURI(cfg.MongoConfig.Uri).
-		SetConnectTimeout(time.Duration(cfg.MongoConfig.ConnectTimeout) * time.Second).
+		SetConnectTimeout(timeout).SetServerSelectionTimeout(timeout).
 		SetMaxConnecting(cfg.MongoConfig.MaxOpenConn).
 		SetMaxPoolSize(cfg.MongoConfig.MaxPoolSize).SetMinPoolSize(cfg.MongoConfig.MinPoolSize)
 	client, err := mongo.NewClient(option)
+	if err != nil {
+		return nil, err
+	}
 
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 	err = client.Connect(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	if err = client.Ping(ctx, readpref.Primary()); err != nil {
+		_ = client.Disconnect(context.Background())
 		return nil, err
 	}
 
@@ -70,3 +78,24 @@ func connectMongo(cfg *cfg.AppConfig) (*Mgo, error) {
 		Db:     client.Database(cfg.MongoConfig.Db),
 	}, nil
 }
+
+// Ping 检查mongo是否可用，尚未建立连接时先建立连接，耗时受connect_timeout限制
+func Ping(ctx context.Context) error {
+	mgo, err := Get()
+	if err != nil {
+		return err
+	}
+	return mgo.Client.Ping(ctx, readpref.Primary())
+}
+
+// Configured 是否已配置mongo
+func Configured() bool {
+	return container.Has[*Mgo](container.Default, "")
+}
+
+// Close 断开mongo连接
+func Close() {
+	if mgo, ok := container.Resolved[*Mgo](container.Default, ""); ok {
+		_ = mgo.Client.Disconnect(context.Background())
+	}
+}
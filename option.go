@@ -0,0 +1,128 @@
+package foundation
+
+import (
+	"context"
+
+	"github.com/easonchen147/foundation/cfg"
+	"github.com/easonchen147/foundation/response"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+)
+
+// Component 应用可选的基础组件
+type Component string
+
+const (
+	ComponentMysql Component = "mysql"
+	ComponentRedis Component = "redis"
+	ComponentMongo Component = "mongo"
+	ComponentKafka Component = "kafka"
+)
+
+// AllComponents 全部基础组件，未配置的组件会被跳过
+var AllComponents = []Component{ComponentMysql, ComponentRedis, ComponentMongo, ComponentKafka}
+
+// Option 应用构建选项
+type Option func(*options)
+
+type options struct {
+	configFile          string
+	envPrefix           *string
+	config              *cfg.AppConfig
+	components          []Component
+	registerRoutes      func(*gin.Engine)
+	registerAdminRoutes func(*gin.RouterGroup)
+	registerGrpc        func(*grpc.Server)
+	grpcOptions         []grpc.ServerOption
+	envelope            response.Envelope
+	recoveryHandler     func(*gin.Context, interface{})
+	migration           func(ctx context.Context, app *App) error
+	commands            []Command
+	modules             []Module
+}
+
+// WithConfigFile 指定配置文件路径，默认读取环境变量CONFIG_FILE或app.toml
+func WithConfigFile(file string) Option {
+	return func(o *options) {
+		o.configFile = file
+	}
+}
+
+// WithEnvPrefix 指定覆盖配置的环境变量前缀，默认为FOUNDATION，为空时不读取环境变量
+func WithEnvPrefix(prefix string) Option {
+	return func(o *options) {
+		o.envPrefix = &prefix
+	}
+}
+
+// WithConfig 直接使用内存中的配置，不再读取配置文件
+func WithConfig(config *cfg.AppConfig) Option {
+	return func(o *options) {
+		o.config = config
+	}
+}
+
+// WithComponents 指定需要初始化的基础组件
+func WithComponents(components ...Component) Option {
+	return func(o *options) {
+		o.components = append(o.components, components...)
+	}
+}
+
+// WithRoutes 注册业务路由
+func WithRoutes(registerRoutes func(*gin.Engine)) Option {
+	return func(o *options) {
+		o.registerRoutes = registerRoutes
+	}
+}
+
+// WithAdminRoutes 注册额外的运维路由，与内置运维接口使用相同的访问控制
+func WithAdminRoutes(registerAdminRoutes func(*gin.RouterGroup)) Option {
+	return func(o *options) {
+		o.registerAdminRoutes = registerAdminRoutes
+	}
+}
+
+// WithGrpc 注册grpc服务，根据grpc_port配置使用独立端口或与http复用端口
+func WithGrpc(registerGrpc func(*grpc.Server), opts ...grpc.ServerOption) Option {
+	return func(o *options) {
+		o.registerGrpc = registerGrpc
+		o.grpcOptions = append(o.grpcOptions, opts...)
+	}
+}
+
+// WithEnvelope 自定义统一响应结构
+func WithEnvelope(envelope response.Envelope) Option {
+	return func(o *options) {
+		o.envelope = envelope
+	}
+}
+
+// WithRecoveryHandler 自定义panic后的响应，默认返回response.ErrInternal，开发环境附带panic信息及堆栈
+func WithRecoveryHandler(handler func(c *gin.Context, err interface{})) Option {
+	return func(o *options) {
+		o.recoveryHandler = handler
+	}
+}
+
+// WithMigration 注册migrate子命令执行的迁移任务
+func WithMigration(migration func(ctx context.Context, app *App) error) Option {
+	return func(o *options) {
+		o.migration = migration
+	}
+}
+
+// WithCommand 注册自定义子命令
+func WithCommand(command Command) Option {
+	return func(o *options) {
+		o.commands = append(o.commands, command)
+	}
+}
+
+// WithModules 注册功能模块，模块按依赖顺序初始化，应用停止时按相反顺序关闭
+func WithModules(modules ...Module) Option {
+	return func(o *options) {
+		o.modules = append(o.modules, modules...)
+	}
+}
@@ -0,0 +1,101 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+
+	"github.com/easonchen147/foundation/log"
+	"github.com/easonchen147/foundation/metrics"
+)
+
+// Conn WebSocket或SSE连接
+type Conn struct {
+	ID        string
+	UserID    string
+	Transport string // websocket或sse
+
+	ctx  context.Context // 携带建立连接时请求的traceId
+	hub  *Hub
+	send chan Message
+
+	mu    sync.Mutex
+	rooms map[string]struct{}
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newConn(ctx context.Context, hub *Hub, id, userID, transport string) *Conn {
+	return &Conn{
+		ID:        id,
+		UserID:    userID,
+		Transport: transport,
+		ctx:       ctx,
+		hub:       hub,
+		send:      make(chan Message, hub.conf.SendBuffer),
+		rooms:     make(map[string]struct{}),
+		closed:    make(chan struct{}),
+	}
+}
+
+// Context 连接的上下文，携带建立连接时请求的traceId，连接关闭后不会取消
+func (c *Conn) Context() context.Context {
+	return c.ctx
+}
+
+// Send 将消息放入发送队列，队列已满说明客户端处理过慢，此时断开连接并返回false
+func (c *Conn) Send(msg Message) bool {
+	select {
+	case <-c.closed:
+		return false
+	default:
+	}
+	select {
+	case c.send <- msg:
+		return true
+	default:
+		log.Warn(c.ctx, "Realtime connection %s of user %s is too slow, send queue is full, closing", c.ID, c.UserID)
+		metrics.RealtimeDropped.WithLabelValues(c.Transport).Inc()
+		c.Close()
+		return false
+	}
+}
+
+// Join 加入房间
+func (c *Conn) Join(room string) {
+	c.mu.Lock()
+	c.rooms[room] = struct{}{}
+	c.mu.Unlock()
+	c.hub.join(c, room)
+}
+
+// Leave 离开房间
+func (c *Conn) Leave(room string) {
+	c.mu.Lock()
+	delete(c.rooms, room)
+	c.mu.Unlock()
+	c.hub.leave(c, room)
+}
+
+// Rooms 已加入的房间
+func (c *Conn) Rooms() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rooms := make([]string, 0, len(c.rooms))
+	for room := range c.rooms {
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+// Close 关闭连接，WebSocket会发送关闭帧，SSE会发送close事件
+func (c *Conn) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+}
+
+// Done 连接关闭时关闭的channel
+func (c *Conn) Done() <-chan struct{} {
+	return c.closed
+}
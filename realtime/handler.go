@@ -0,0 +1,231 @@
+package realtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/easonchen147/foundation/constant"
+	"github.com/easonchen147/foundation/log"
+	"github.com/easonchen147/foundation/metrics"
+	"github.com/easonchen147/foundation/response"
+	"github.com/easonchen147/foundation/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	TransportWebSocket = "websocket"
+	TransportSSE       = "sse"
+)
+
+// Session 连接的身份信息
+type Session struct {
+	UserID string   // 为空时只能接收房间消息与广播
+	Rooms  []string // 建立连接时加入的房间
+}
+
+// Authenticator 建立连接前校验请求并返回身份信息，返回的错误通过response.Fail响应，如response.ErrUnauthorized
+type Authenticator func(c *gin.Context) (Session, error)
+
+// WebSocket 创建WebSocket接口，checkOrigin为nil时只允许同源请求
+func (h *Hub) WebSocket(auth Authenticator, checkOrigin func(r *http.Request) bool) gin.HandlerFunc {
+	upgrader := websocket.Upgrader{CheckOrigin: checkOrigin}
+	return func(c *gin.Context) {
+		session, ok := h.authenticate(c, auth)
+		if !ok {
+			return
+		}
+		ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// Upgrade失败时已经返回了错误响应
+			log.Warn(c, "Realtime websocket upgrade failed, error: %v", err)
+			return
+		}
+		defer ws.Close()
+
+		conn := newConn(connContext(c), h, util.GetNanoId(), session.UserID, TransportWebSocket)
+		if err = h.register(conn, session.Rooms); err != nil {
+			_ = ws.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down"), time.Now().Add(time.Second))
+			return
+		}
+		defer h.unregister(conn)
+		h.track(conn)
+		defer h.untrack(conn)
+
+		writerDone := make(chan struct{})
+		go func() {
+			defer close(writerDone)
+			h.writeWebSocket(conn, ws)
+		}()
+		h.readWebSocket(conn, ws)
+		conn.Close()
+		<-writerDone
+	}
+}
+
+// 读取客户端消息，超过两个心跳间隔未收到任何数据或pong时断开
+func (h *Hub) readWebSocket(conn *Conn, ws *websocket.Conn) {
+	timeout := 2 * h.conf.HeartbeatInterval
+	ws.SetReadLimit(h.conf.MaxMessageSize)
+	_ = ws.SetReadDeadline(time.Now().Add(timeout))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(timeout))
+	})
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) && !isClosed(conn) {
+				log.Info(conn.ctx, "Realtime websocket %s read failed, error: %v", conn.ID, err)
+			}
+			return
+		}
+		_ = ws.SetReadDeadline(time.Now().Add(timeout))
+		if h.conf.OnMessage != nil {
+			h.conf.OnMessage(conn, data)
+		}
+	}
+}
+
+func (h *Hub) writeWebSocket(conn *Conn, ws *websocket.Conn) {
+	ticker := time.NewTicker(h.conf.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg := <-conn.send:
+			_ = ws.SetWriteDeadline(time.Now().Add(h.conf.WriteTimeout))
+			if err := ws.WriteJSON(msg); err != nil {
+				conn.Close()
+				_ = ws.Close()
+				return
+			}
+		case <-ticker.C:
+			if err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.conf.WriteTimeout)); err != nil {
+				conn.Close()
+				_ = ws.Close()
+				return
+			}
+		case <-conn.closed:
+			// 发送关闭帧后等待客户端响应，超时后由读取超时结束连接
+			_ = ws.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(h.conf.WriteTimeout))
+			_ = ws.SetReadDeadline(time.Now().Add(time.Second))
+			return
+		}
+	}
+}
+
+// SSE 创建Server-Sent Events接口，心跳以注释行发送
+func (h *Hub) SSE(auth Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, ok := h.authenticate(c, auth)
+		if !ok {
+			return
+		}
+		conn := newConn(connContext(c), h, util.GetNanoId(), session.UserID, TransportSSE)
+		if err := h.register(conn, session.Rooms); err != nil {
+			response.Fail(c, response.ErrServiceUnavailable)
+			return
+		}
+		defer h.unregister(conn)
+		h.track(conn)
+		defer h.untrack(conn)
+
+		header := c.Writer.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		header.Set("X-Accel-Buffering", "no") // 禁止nginx缓冲
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		ticker := time.NewTicker(h.conf.HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case msg := <-conn.send:
+				if _, err := c.Writer.Write(encodeEvent(msg)); err != nil {
+					return
+				}
+				c.Writer.Flush()
+			case <-ticker.C:
+				if _, err := c.Writer.WriteString(": ping\n\n"); err != nil {
+					return
+				}
+				c.Writer.Flush()
+			case <-conn.closed:
+				_, _ = c.Writer.Write(encodeEvent(Message{Event: "close"}))
+				c.Writer.Flush()
+				return
+			case <-c.Request.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+// 按SSE格式编码消息，data中的换行拆分为多个data行
+func encodeEvent(msg Message) []byte {
+	var buf bytes.Buffer
+	if msg.Event != "" {
+		buf.WriteString("event: ")
+		buf.WriteString(msg.Event)
+		buf.WriteByte('\n')
+	}
+	data := []byte(msg.Data)
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+func (h *Hub) authenticate(c *gin.Context, auth Authenticator) (Session, bool) {
+	if auth == nil {
+		return Session{}, true
+	}
+	session, err := auth(c)
+	if err != nil {
+		var bizErr *response.BizError
+		if !errors.As(err, &bizErr) {
+			log.Warn(c, "Realtime authenticate failed, error: %v", err)
+			err = response.ErrUnauthorized
+		}
+		response.Fail(c, err)
+		return Session{}, false
+	}
+	return session, true
+}
+
+func (h *Hub) track(conn *Conn) {
+	metrics.RealtimeConnections.WithLabelValues(conn.Transport).Inc()
+	log.Info(conn.ctx, "Realtime %s connection %s of user %s opened", conn.Transport, conn.ID, conn.UserID)
+}
+
+func (h *Hub) untrack(conn *Conn) {
+	metrics.RealtimeConnections.WithLabelValues(conn.Transport).Dec()
+	log.Info(conn.ctx, "Realtime %s connection %s of user %s closed", conn.Transport, conn.ID, conn.UserID)
+}
+
+// 连接的上下文只保留traceId，不随请求结束而取消
+func connContext(c *gin.Context) context.Context {
+	return context.WithValue(context.Background(), constant.TraceIdKey, c.GetString(constant.TraceIdKey))
+}
+
+func isClosed(conn *Conn) bool {
+	select {
+	case <-conn.closed:
+		return true
+	default:
+		return false
+	}
+}
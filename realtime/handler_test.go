@@ -0,0 +1,125 @@
+package realtime
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// 以query中的user、room作为身份信息
+func queryAuth(c *gin.Context) (Session, error) {
+	return Session{UserID: c.Query("user"), Rooms: c.QueryArray("room")}, nil
+}
+
+// 启动挂载了hub接口的测试服务，测试结束时先关闭hub再关闭服务
+func serveHub(t *testing.T, hub *Hub) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/events", hub.SSE(queryAuth))
+	router.GET("/ws", hub.WebSocket(queryAuth, nil))
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = hub.Close(ctx)
+	})
+	return server
+}
+
+// 建立SSE连接，返回依次收到的事件名称
+func connectSSE(t *testing.T, url string) <-chan string {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s = %d, want 200", url, resp.StatusCode)
+	}
+
+	events := make(chan string, 16)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if event, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+				events <- event
+			}
+		}
+	}()
+	return events
+}
+
+func nextEvent(t *testing.T, events <-chan string) string {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+		return ""
+	}
+}
+
+func TestEncodeEventSplitsDataLines(t *testing.T) {
+	got := string(encodeEvent(Message{Event: "chat", Data: json.RawMessage("{\"a\":1,\n\"b\":2}")}))
+	want := "event: chat\ndata: {\"a\":1,\ndata: \"b\":2}\n\n"
+	if got != want {
+		t.Errorf("encodeEvent() = %q, want %q", got, want)
+	}
+}
+
+func TestEncodeEventWithoutEvent(t *testing.T) {
+	if got := string(encodeEvent(Message{})); got != "data: {}\n\n" {
+		t.Errorf("encodeEvent() = %q, want %q", got, "data: {}\n\n")
+	}
+}
+
+func TestSSERefusedAfterClose(t *testing.T) {
+	hub := NewHub(Config{})
+	server := serveHub(t, hub)
+	if err := hub.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(server.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("GET /events = %d, want 503", resp.StatusCode)
+	}
+}
+
+func TestWebSocketRefusedAfterClose(t *testing.T) {
+	hub := NewHub(Config{})
+	server := serveHub(t, hub)
+	if err := hub.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = ws.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseGoingAway {
+		t.Errorf("ReadMessage() error = %v, want going away close", err)
+	}
+}
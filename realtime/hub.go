@@ -0,0 +1,310 @@
+// Package realtime 提供WebSocket与SSE推送，Hub按用户、房间管理连接，配置redis后通过pub/sub在多个实例间广播
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/easonchen147/foundation"
+	"github.com/easonchen147/foundation/log"
+	"github.com/easonchen147/foundation/middleware"
+	"github.com/easonchen147/foundation/worker"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrHubClosed hub已关闭
+var ErrHubClosed = errors.New("realtime hub is closed")
+
+// Message 推送消息，WebSocket以json发送，SSE以event与data字段发送
+type Message struct {
+	Event string          `json:"event,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// NewMessage 将data序列化为json创建消息
+func NewMessage(event string, data interface{}) (Message, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Event: event, Data: raw}, nil
+}
+
+// Config hub配置
+type Config struct {
+	// Name hub名称，作为后台任务及停止钩子的名称，同一应用中有多个hub时需要区分，default realtime
+	Name string
+
+	SendBuffer        int           // 每个连接待发送消息的队列长度，队列满时断开连接，default 64
+	HeartbeatInterval time.Duration // 心跳间隔，default 30s，WebSocket超过两个心跳间隔未收到响应时断开连接
+	WriteTimeout      time.Duration // WebSocket单条消息的写超时，default 10s
+	MaxMessageSize    int64         // WebSocket客户端消息的最大长度，default 64KB
+
+	// Redis 用于多实例广播，可使用cache.Redis()或cache.RedisCluster()，为nil时只在本实例内推送
+	Redis   redis.UniversalClient
+	Channel string // redis pub/sub的频道，default foundation:realtime
+
+	// OnMessage 处理WebSocket客户端发送的消息
+	OnMessage func(conn *Conn, data []byte)
+
+	// Routes 挂载WebSocket、SSE接口的路由模板，如"/ws"，Attach时注册为不参与并发限流
+	Routes []string
+}
+
+// Hub 连接管理与消息分发
+type Hub struct {
+	conf Config
+
+	mu     sync.RWMutex
+	conns  map[*Conn]struct{}
+	users  map[string]map[*Conn]struct{}
+	rooms  map[string]map[*Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup // 处理中的连接
+}
+
+// NewHub 创建hub
+func NewHub(conf Config) *Hub {
+	if conf.Name == "" {
+		conf.Name = "realtime"
+	}
+	if conf.SendBuffer <= 0 {
+		conf.SendBuffer = 64
+	}
+	if conf.HeartbeatInterval <= 0 {
+		conf.HeartbeatInterval = 30 * time.Second
+	}
+	if conf.WriteTimeout <= 0 {
+		conf.WriteTimeout = 10 * time.Second
+	}
+	if conf.MaxMessageSize <= 0 {
+		conf.MaxMessageSize = 64 << 10
+	}
+	if conf.Channel == "" {
+		conf.Channel = "foundation:realtime"
+	}
+	return &Hub{
+		conf:  conf,
+		conns: make(map[*Conn]struct{}),
+		users: make(map[string]map[*Conn]struct{}),
+		rooms: make(map[string]map[*Conn]struct{}),
+	}
+}
+
+// Attach 将hub接入应用的生命周期：配置redis时以后台任务订阅广播消息，应用停止时在排空http请求之前关闭所有连接，
+// 并将Routes注册为不参与并发限流
+func (h *Hub) Attach(app *foundation.App) error {
+	middleware.ExemptShed(h.conf.Routes...)
+	if h.conf.Redis != nil {
+		if err := app.RegisterWorker(h.conf.Name, h.Run, worker.WithRestart(time.Second, 30*time.Second)); err != nil {
+			return err
+		}
+	}
+	app.OnStop(foundation.Hook{Name: h.conf.Name, Order: foundation.StopOrderConn, Fn: h.Close})
+	return nil
+}
+
+// 广播消息的目标
+const (
+	targetAll  = "all"
+	targetUser = "user"
+	targetRoom = "room"
+)
+
+type envelope struct {
+	Target string  `json:"target"`
+	Key    string  `json:"key,omitempty"`
+	Msg    Message `json:"msg"`
+}
+
+// SendToUser 向用户的所有连接推送消息
+func (h *Hub) SendToUser(ctx context.Context, userID string, msg Message) error {
+	return h.publish(ctx, envelope{Target: targetUser, Key: userID, Msg: msg})
+}
+
+// SendToRoom 向房间内的所有连接推送消息
+func (h *Hub) SendToRoom(ctx context.Context, room string, msg Message) error {
+	return h.publish(ctx, envelope{Target: targetRoom, Key: room, Msg: msg})
+}
+
+// Broadcast 向所有连接推送消息
+func (h *Hub) Broadcast(ctx context.Context, msg Message) error {
+	return h.publish(ctx, envelope{Target: targetAll, Msg: msg})
+}
+
+// 配置redis时发布到频道，由每个实例(包括本实例)订阅后推送，否则直接推送到本实例的连接
+func (h *Hub) publish(ctx context.Context, env envelope) error {
+	if h.conf.Redis == nil {
+		h.deliver(env)
+		return nil
+	}
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return h.conf.Redis.Publish(ctx, h.conf.Channel, payload).Err()
+}
+
+func (h *Hub) deliver(env envelope) {
+	h.mu.RLock()
+	var targets []*Conn
+	switch env.Target {
+	case targetAll:
+		targets = make([]*Conn, 0, len(h.conns))
+		for conn := range h.conns {
+			targets = append(targets, conn)
+		}
+	case targetUser:
+		for conn := range h.users[env.Key] {
+			targets = append(targets, conn)
+		}
+	case targetRoom:
+		for conn := range h.rooms[env.Key] {
+			targets = append(targets, conn)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, conn := range targets {
+		conn.Send(env.Msg)
+	}
+}
+
+// Run 订阅redis频道并推送收到的消息，直到ctx取消，未配置redis时直接等待ctx取消
+func (h *Hub) Run(ctx context.Context) error {
+	if h.conf.Redis == nil {
+		<-ctx.Done()
+		return nil
+	}
+	pubsub := h.conf.Redis.Subscribe(ctx, h.conf.Channel)
+	defer pubsub.Close()
+	// 等待订阅成功，保证启动后发布的消息不会丢失
+	if _, err := pubsub.Receive(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case redisMsg, ok := <-ch:
+			if !ok {
+				return errors.New("realtime redis subscription closed")
+			}
+			var env envelope
+			if err := json.Unmarshal([]byte(redisMsg.Payload), &env); err != nil {
+				log.Warn(ctx, "Realtime drop invalid message, error: %v", err)
+				continue
+			}
+			h.deliver(env)
+		}
+	}
+}
+
+// Close 关闭所有连接并等待连接处理结束，关闭后不再接受新连接
+func (h *Hub) Close(ctx context.Context) error {
+	h.mu.Lock()
+	h.closed = true
+	conns := make([]*Conn, 0, len(h.conns))
+	for conn := range h.conns {
+		conns = append(conns, conn)
+	}
+	h.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Count 当前连接数
+func (h *Hub) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+func (h *Hub) register(conn *Conn, rooms []string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return ErrHubClosed
+	}
+	h.wg.Add(1)
+	h.conns[conn] = struct{}{}
+	if conn.UserID != "" {
+		addTo(h.users, conn.UserID, conn)
+	}
+	for _, room := range rooms {
+		conn.rooms[room] = struct{}{}
+		addTo(h.rooms, room, conn)
+	}
+	return nil
+}
+
+func (h *Hub) unregister(conn *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.conns[conn]; !ok {
+		return
+	}
+	delete(h.conns, conn)
+	removeFrom(h.users, conn.UserID, conn)
+	conn.mu.Lock()
+	for room := range conn.rooms {
+		removeFrom(h.rooms, room, conn)
+	}
+	conn.mu.Unlock()
+	h.wg.Done()
+}
+
+func (h *Hub) join(conn *Conn, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.conns[conn]; ok {
+		addTo(h.rooms, room, conn)
+	}
+}
+
+func (h *Hub) leave(conn *Conn, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	removeFrom(h.rooms, room, conn)
+}
+
+func addTo(index map[string]map[*Conn]struct{}, key string, conn *Conn) {
+	set, ok := index[key]
+	if !ok {
+		set = make(map[*Conn]struct{})
+		index[key] = set
+	}
+	set[conn] = struct{}{}
+}
+
+func removeFrom(index map[string]map[*Conn]struct{}, key string, conn *Conn) {
+	if set, ok := index[key]; ok {
+		delete(set, conn)
+		if len(set) == 0 {
+			delete(index, key)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/easonchen147/foundation/foundationtest"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestDeliverToUserAndRoom(t *testing.T) {
+	hub := NewHub(Config{})
+	server := serveHub(t, hub)
+	alice := connectSSE(t, server.URL+"/events?user=alice&room=r1")
+	bob := connectSSE(t, server.URL+"/events?user=bob&room=r2")
+
+	ctx := context.Background()
+	for _, send := range []func() error{
+		func() error { return hub.SendToUser(ctx, "alice", Message{Event: "direct"}) },
+		func() error { return hub.SendToRoom(ctx, "r2", Message{Event: "room"}) },
+		func() error { return hub.Broadcast(ctx, Message{Event: "all"}) },
+	} {
+		if err := send(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 每个连接按发送顺序收到消息，广播之前收到的即为定向消息
+	if got := nextEvent(t, alice); got != "direct" {
+		t.Errorf("alice first event = %q, want direct", got)
+	}
+	if got := nextEvent(t, alice); got != "all" {
+		t.Errorf("alice second event = %q, want all", got)
+	}
+	if got := nextEvent(t, bob); got != "room" {
+		t.Errorf("bob first event = %q, want room", got)
+	}
+	if got := nextEvent(t, bob); got != "all" {
+		t.Errorf("bob second event = %q, want all", got)
+	}
+}
+
+// 通过redis pub/sub将一个实例发送的消息推送到其他实例的连接
+func TestDeliverAcrossInstances(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	sender, receiver := NewHub(Config{Redis: client}), NewHub(Config{Redis: client})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	t.Cleanup(wg.Wait)
+	t.Cleanup(cancel)
+	for _, hub := range []*Hub{sender, receiver} {
+		hub := hub
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = hub.Run(ctx)
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for mr.PubSubNumSub(receiver.conf.Channel)[receiver.conf.Channel] < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("hubs did not subscribe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	alice := connectSSE(t, serveHub(t, receiver).URL+"/events?user=alice")
+	if err := sender.SendToUser(ctx, "alice", Message{Event: "direct"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := nextEvent(t, alice); got != "direct" {
+		t.Errorf("alice event = %q, want direct", got)
+	}
+}
+
+// 同一应用中名称不同的hub可以同时接入
+func TestAttachNamedHubs(t *testing.T) {
+	env := foundationtest.New(t, func(*gin.Engine) {}, foundationtest.WithDatabases())
+	client := redis.NewClient(&redis.Options{Addr: env.Redis.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	for _, name := range []string{"chat", "notify"} {
+		if err := NewHub(Config{Name: name, Redis: client}).Attach(env.App); err != nil {
+			t.Errorf("Attach(%s) error = %v", name, err)
+		}
+	}
+}
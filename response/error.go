@@ -0,0 +1,61 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// BizError 业务错误，Code在应用内唯一
+type BizError struct {
+	Code       int    `json:"code"`
+	Msg        string `json:"msg"`
+	HTTPStatus int    `json:"-"`
+}
+
+func (e *BizError) Error() string {
+	return fmt.Sprintf("code: %d, msg: %s", e.Code, e.Msg)
+}
+
+// WithMsg 复制错误并替换提示信息
+func (e *BizError) WithMsg(msg string) *BizError {
+	return &BizError{Code: e.Code, Msg: msg, HTTPStatus: e.HTTPStatus}
+}
+
+var (
+	mu       sync.RWMutex
+	registry = make(map[int]*BizError)
+)
+
+// NewError 创建并注册业务错误，Code重复时panic
+func NewError(code int, msg string, httpStatus int) *BizError {
+	mu.Lock()
+	defer mu.Unlock()
+	if existed, ok := registry[code]; ok {
+		panic(fmt.Sprintf("biz error code %d already registered: %s", code, existed.Msg))
+	}
+	err := &BizError{Code: code, Msg: msg, HTTPStatus: httpStatus}
+	registry[code] = err
+	return err
+}
+
+// Lookup 根据Code查找已注册的业务错误
+func Lookup(code int) (*BizError, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	err, ok := registry[code]
+	return err, ok
+}
+
+// 内置错误码
+var (
+	ErrInternal           = NewError(-1, "服务器异常，请稍后再试", http.StatusInternalServerError)
+	ErrBadRequest         = NewError(400, "请求参数错误", http.StatusBadRequest)
+	ErrUnauthorized       = NewError(401, "未登录或登录已过期", http.StatusUnauthorized)
+	ErrForbidden          = NewError(403, "没有访问权限", http.StatusForbidden)
+	ErrNotFound           = NewError(404, "资源不存在", http.StatusNotFound)
+	ErrTooManyRequests    = NewError(429, "请求过于频繁，请稍后再试", http.StatusTooManyRequests)
+	ErrServiceUnavailable = NewError(503, "服务繁忙，请稍后再试", http.StatusServiceUnavailable)
+	ErrInvalidSign        = NewError(10001, "签名校验失败", http.StatusBadRequest)
+	ErrInvalidTs          = NewError(10002, "请求时间戳无效或已过期", http.StatusBadRequest)
+)
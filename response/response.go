@@ -0,0 +1,70 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/easonchen147/foundation/constant"
+	"github.com/easonchen147/foundation/log"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	CodeSuccess = 0
+	MsgSuccess  = "success"
+)
+
+// Body 默认的响应结构
+type Body struct {
+	Code    int         `json:"code"`
+	Msg     string      `json:"msg"`
+	Data    interface{} `json:"data,omitempty"`
+	TraceId string      `json:"traceId"`
+}
+
+// Envelope 根据错误码、提示信息及数据构造响应体
+type Envelope func(c *gin.Context, code int, msg string, data interface{}) interface{}
+
+var envelope Envelope = defaultEnvelope
+
+func defaultEnvelope(c *gin.Context, code int, msg string, data interface{}) interface{} {
+	return &Body{
+		Code:    code,
+		Msg:     msg,
+		Data:    data,
+		TraceId: c.GetString(constant.TraceIdKey),
+	}
+}
+
+// SetEnvelope 替换响应结构，传入nil时恢复默认结构
+func SetEnvelope(e Envelope) {
+	if e == nil {
+		e = defaultEnvelope
+	}
+	envelope = e
+}
+
+// OK 返回成功响应
+func OK(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusOK, envelope(c, CodeSuccess, MsgSuccess, data))
+}
+
+// Fail 返回失败响应并中止后续处理，非BizError的错误统一返回ErrInternal且不暴露错误详情
+func Fail(c *gin.Context, err error) {
+	var bizErr *BizError
+	if !errors.As(err, &bizErr) {
+		log.Error(c, "request failed, error: %v", err)
+		bizErr = ErrInternal
+	}
+	FailWithData(c, bizErr, nil)
+}
+
+// FailWithData 返回带数据的失败响应并中止后续处理
+func FailWithData(c *gin.Context, bizErr *BizError, data interface{}) {
+	status := bizErr.HTTPStatus
+	if status == 0 {
+		status = http.StatusOK
+	}
+	c.AbortWithStatusJSON(status, envelope(c, bizErr.Code, bizErr.Msg, data))
+}
@@ -0,0 +1,111 @@
+package rpc
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	"github.com/easonchen147/foundation/constant"
+	"github.com/easonchen147/foundation/log"
+	"github.com/easonchen147/foundation/util"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryTrace 从请求metadata中读取traceId，不存在时生成新的traceId，并通过响应header返回
+func UnaryTrace() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withTraceId(ctx), req)
+	}
+}
+
+// StreamTrace 流式接口的traceId拦截器
+func StreamTrace() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: withTraceId(ss.Context())})
+	}
+}
+
+// UnaryLogger 记录访问日志
+func UnaryLogger() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		startTime := time.Now()
+		resp, err := handler(ctx, req)
+		accessLog(ctx, info.FullMethod, startTime, err)
+		return resp, err
+	}
+}
+
+// StreamLogger 流式接口的访问日志
+func StreamLogger() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		startTime := time.Now()
+		err := handler(srv, ss)
+		accessLog(ss.Context(), info.FullMethod, startTime, err)
+		return err
+	}
+}
+
+// UnaryRecovery 捕获panic并返回Internal错误
+func UnaryRecovery() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverError(ctx, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecovery 流式接口的panic捕获
+func StreamRecovery() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverError(ss.Context(), info.FullMethod, r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func withTraceId(ctx context.Context) context.Context {
+	var traceId string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(constant.TraceIdMetadataKey); len(values) > 0 {
+			traceId = values[0]
+		}
+	}
+	if traceId == "" {
+		traceId = util.GetNanoId()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(constant.TraceIdMetadataKey, traceId))
+	return context.WithValue(ctx, constant.TraceIdKey, traceId)
+}
+
+func accessLog(ctx context.Context, method string, startTime time.Time, err error) {
+	log.Access(ctx, "RpcLog",
+		zap.String("code", status.Code(err).String()),
+		zap.String("method", method),
+		zap.Duration("cost", time.Since(startTime)))
+}
+
+func recoverError(ctx context.Context, method string, r interface{}) error {
+	log.Error(ctx, "grpc panic recovery, method: %s, error: %v\n%s", method, r, debug.Stack())
+	return status.Error(codes.Internal, "服务器异常，请稍后再试")
+}
+
+// wrappedStream 替换ServerStream的上下文
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrappedStream) Context() context.Context {
+	return w.ctx
+}
@@ -0,0 +1,91 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+)
+
+// NewServer 创建grpc服务，默认带有traceId、访问日志及panic恢复拦截器
+func NewServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(UnaryTrace(), UnaryLogger(), UnaryRecovery()),
+		grpc.ChainStreamInterceptor(StreamTrace(), StreamLogger(), StreamRecovery()),
+	}, opts...)
+	return grpc.NewServer(opts...)
+}
+
+// GracefulStop 优雅停止grpc服务，ctx超时后强制关闭剩余连接
+func GracefulStop(ctx context.Context, server *grpc.Server) error {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		return ctx.Err()
+	}
+}
+
+// Mux 在同一端口上同时提供http与grpc服务，根据请求协议及Content-Type分发
+type Mux struct {
+	grpcServer *grpc.Server
+	handler    http.Handler
+
+	wg      sync.WaitGroup
+	closing atomic.Bool
+}
+
+// NewMux 创建复用端口的处理器
+func NewMux(grpcServer *grpc.Server, httpHandler http.Handler) *Mux {
+	return &Mux{grpcServer: grpcServer, handler: httpHandler}
+}
+
+// Handler 获取用于http.Server的处理器，未启用tls时通过h2c支持明文http2
+func (m *Mux) Handler(tlsEnabled bool) http.Handler {
+	if tlsEnabled {
+		return m
+	}
+	return h2c.NewHandler(m, &http2.Server{})
+}
+
+func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 2 || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		m.handler.ServeHTTP(w, r)
+		return
+	}
+	if m.closing.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	m.wg.Add(1)
+	defer m.wg.Done()
+	m.grpcServer.ServeHTTP(w, r)
+}
+
+// Drain 拒绝新的grpc请求并等待处理中的请求结束，ctx超时后强制关闭
+func (m *Mux) Drain(ctx context.Context) error {
+	m.closing.Store(true)
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	defer m.grpcServer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
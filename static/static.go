@@ -0,0 +1,271 @@
+// Package static 在gin上挂载静态资源及单页应用，支持embed.FS与目录(os.DirFS)，
+// 提供强ETag、带hash资源的长期缓存、预压缩的.br/.gz文件以及单页应用回退到index.html
+package static
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/easonchen147/foundation/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	cacheImmutable = "public, max-age=31536000, immutable"
+	cacheNoCache   = "no-cache"
+)
+
+// 构建工具生成的带hash文件名，如index-4f3a2b1c.js、app.8d2c0e1f.css、main-BXk2c9_d.js，
+// 扩展名前的最后一段同时包含字母和数字，且为至少8位的小写hex或8到12位不含单词的base64url时才视为hash，
+// 避免user-settings.js、my-component2.js等普通文件名被长期缓存，未识别的hash只是不长期缓存
+var (
+	hashedPattern = regexp.MustCompile(`[.-]([A-Za-z0-9_]{8,})\.[A-Za-z0-9]+$`)
+	hexHash       = regexp.MustCompile(`^[0-9a-f]{8,}$`)
+	base64Hash    = regexp.MustCompile(`^[A-Za-z0-9_]{8,12}$`)
+	wordLike      = regexp.MustCompile(`[a-z]{4,}|[A-Z]{4,}`)
+	hasDigit      = regexp.MustCompile(`[0-9]`)
+	hasLetter     = regexp.MustCompile(`[A-Za-z]`)
+)
+
+// Config 静态资源配置
+type Config struct {
+	Prefix string // 挂载路径，如"/admin"，为空或"/"时挂载为NoRoute处理
+	Index  string // 目录的默认文件，default index.html
+	// SPA 为true时不带扩展名且不存在的路径返回index.html，由前端路由处理
+	SPA bool
+	// ExcludePrefixes 不回退到index.html的路径前缀，如"/api"，未匹配到路由时返回统一的404响应
+	ExcludePrefixes []string
+	// Immutable 判断文件是否可以长期缓存，默认根据文件名中是否带有hash判断，html文件始终不长期缓存
+	Immutable func(name string) bool
+}
+
+// Mount 挂载静态资源，fsys可以是embed.FS(通常配合fs.Sub去掉目录前缀)或os.DirFS
+func Mount(engine *gin.Engine, fsys fs.FS, conf Config) {
+	handler := Handler(fsys, conf)
+	prefix := strings.TrimSuffix(conf.Prefix, "/")
+	if prefix == "" {
+		engine.NoRoute(handler)
+		return
+	}
+	engine.GET(prefix, handler)
+	engine.HEAD(prefix, handler)
+	engine.GET(prefix+"/*filepath", handler)
+	engine.HEAD(prefix+"/*filepath", handler)
+}
+
+// Handler 创建静态资源处理方法，请求路径去掉Prefix后作为文件路径
+func Handler(fsys fs.FS, conf Config) gin.HandlerFunc {
+	if conf.Index == "" {
+		conf.Index = "index.html"
+	}
+	if conf.Immutable == nil {
+		conf.Immutable = isHashed
+	}
+	prefix := strings.TrimSuffix(conf.Prefix, "/")
+	s := &server{fsys: fsys, conf: conf}
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			response.Fail(c, response.ErrNotFound)
+			return
+		}
+		urlPath := c.Request.URL.Path
+		for _, exclude := range conf.ExcludePrefixes {
+			if urlPath == exclude || strings.HasPrefix(urlPath, strings.TrimSuffix(exclude, "/")+"/") {
+				response.Fail(c, response.ErrNotFound)
+				return
+			}
+		}
+		name := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(urlPath, prefix)), "/")
+		if !s.serve(c, name) {
+			response.Fail(c, response.ErrNotFound)
+		}
+	}
+}
+
+type server struct {
+	fsys fs.FS
+	conf Config
+
+	etags sync.Map // 文件标识 -> etag
+}
+
+// 查找并返回文件，文件不存在时返回false
+func (s *server) serve(c *gin.Context, name string) bool {
+	if name == "" {
+		name = s.conf.Index
+	}
+	info, err := fs.Stat(s.fsys, name)
+	if err == nil && info.IsDir() {
+		name = path.Join(name, s.conf.Index)
+		info, err = fs.Stat(s.fsys, name)
+	}
+	if err != nil {
+		if !s.conf.SPA || path.Ext(name) != "" {
+			return false
+		}
+		name = s.conf.Index
+		if info, err = fs.Stat(s.fsys, name); err != nil {
+			return false
+		}
+	}
+	if info.IsDir() {
+		return false
+	}
+
+	header := c.Writer.Header()
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	if !isHTML(name) && s.conf.Immutable(name) {
+		header.Set("Cache-Control", cacheImmutable)
+	} else {
+		header.Set("Cache-Control", cacheNoCache)
+	}
+
+	servedName, servedInfo := name, info
+	if encoding, variant, variantInfo := s.precompressed(c, name); encoding != "" {
+		header.Set("Content-Encoding", encoding)
+		servedName, servedInfo = variant, variantInfo
+	}
+
+	content, err := s.open(servedName)
+	if err != nil {
+		return false
+	}
+	if closer, ok := content.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if contentType == "" {
+		// 无法根据扩展名判断时按内容判断，需要使用未压缩的内容
+		header.Set("Content-Type", s.sniff(name))
+	}
+	etag, err := s.etag(servedName, servedInfo, content)
+	if err != nil {
+		return false
+	}
+	header.Set("ETag", etag)
+	http.ServeContent(c.Writer, c.Request, name, info.ModTime(), content)
+	return true
+}
+
+// 客户端支持时优先使用预压缩的.br、.gz文件
+func (s *server) precompressed(c *gin.Context, name string) (string, string, fs.FileInfo) {
+	accept := c.GetHeader("Accept-Encoding")
+	found := false
+	for _, candidate := range []struct{ encoding, ext string }{{"br", ".br"}, {"gzip", ".gz"}} {
+		info, err := fs.Stat(s.fsys, name+candidate.ext)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		found = true
+		if acceptsEncoding(accept, candidate.encoding) {
+			c.Writer.Header().Add("Vary", "Accept-Encoding")
+			return candidate.encoding, name + candidate.ext, info
+		}
+	}
+	if found {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+	}
+	return "", "", nil
+}
+
+func acceptsEncoding(accept, encoding string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		if strings.TrimSpace(fields[0]) != encoding {
+			continue
+		}
+		for _, param := range fields[1:] {
+			if q := strings.ReplaceAll(param, " ", ""); q == "q=0" || q == "q=0.0" || q == "q=0.00" || q == "q=0.000" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// 打开文件，fs.File未实现io.ReadSeeker时读取到内存中
+func (s *server) open(name string) (io.ReadSeeker, error) {
+	file, err := s.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if seeker, ok := file.(io.ReadSeeker); ok {
+		return &readSeekCloser{ReadSeeker: seeker, file: file}, nil
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (s *server) sniff(name string) string {
+	file, err := s.fsys.Open(name)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer file.Close()
+	buf := make([]byte, 512)
+	n, _ := io.ReadFull(file, buf)
+	return http.DetectContentType(buf[:n])
+}
+
+// 根据文件内容计算强ETag，文件大小与修改时间不变时复用计算结果
+func (s *server) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	key := name + "|" + info.ModTime().Format(time.RFC3339Nano) + "|" + strconv.FormatInt(info.Size(), 10)
+	if etag, ok := s.etags.Load(key); ok {
+		return etag.(string), nil
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	s.etags.Store(key, etag)
+	return etag, nil
+}
+
+// readSeekCloser 在响应完成后关闭文件
+type readSeekCloser struct {
+	io.ReadSeeker
+	file fs.File
+}
+
+func (r *readSeekCloser) Close() error {
+	return r.file.Close()
+}
+
+// 文件名中是否带有构建工具生成的hash
+func isHashed(name string) bool {
+	m := hashedPattern.FindStringSubmatch(path.Base(name))
+	if m == nil || !hasDigit.MatchString(m[1]) || !hasLetter.MatchString(m[1]) {
+		return false
+	}
+	return hexHash.MatchString(m[1]) || (base64Hash.MatchString(m[1]) && !wordLike.MatchString(m[1]))
+}
+
+// html作为入口文件需要每次校验，即使带有hash也不长期缓存
+func isHTML(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".html" || ext == ".htm"
+}
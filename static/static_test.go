@@ -0,0 +1,78 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIsHashed(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"assets/index-4f3a2b1c.js", true},
+		{"assets/app.8d2c0e1f.css", true},
+		{"assets/main-BXk2c9_d.js", true},
+		{"chunk.3f2a1b4c5d6e7f80.js", true},
+		{"user-settings.js", false},
+		{"logo-original.png", false},
+		{"app-dashboard.html", false},
+		{"logo-original-2x.png", false},
+		{"report-20240101.pdf", false},
+		{"app.js", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isHashed(tt.name); got != tt.want {
+				t.Errorf("isHashed(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// 带数字的普通单词不是hash
+func TestIsHashedRejectsWordsWithDigits(t *testing.T) {
+	for _, name := range []string{"my-component2.js", "app.settings1.css", "page-myButton12.js", "vendor.LICENSE2024.txt"} {
+		if isHashed(name) {
+			t.Errorf("isHashed(%q) = true, want false", name)
+		}
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fsys := fstest.MapFS{
+		"index.html":               {Data: []byte("<html></html>")},
+		"page-4f3a2b1c.html":       {Data: []byte("<html></html>")},
+		"assets/index-4f3a2b1c.js": {Data: []byte("console.log(1)")},
+		"user-settings.js":         {Data: []byte("console.log(2)")},
+	}
+	router := gin.New()
+	Mount(router, fsys, Config{})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/assets/index-4f3a2b1c.js", cacheImmutable},
+		{"/user-settings.js", cacheNoCache},
+		{"/page-4f3a2b1c.html", cacheNoCache},
+		{"/", cacheNoCache},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET %s = %d", tt.path, w.Code)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package tlscert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/easonchen147/foundation/log"
+)
+
+// 证书文件变更的检查间隔
+const watchInterval = 10 * time.Second
+
+// Reloader 证书加载器，证书文件变更或收到SIGHUP信号时重新加载，加载失败时继续使用旧证书
+type Reloader struct {
+	certFile     string
+	keyFile      string
+	clientCaFile string
+
+	cert      atomic.Pointer[tls.Certificate]
+	clientCas atomic.Pointer[x509.CertPool]
+	modTime   atomic.Int64
+}
+
+// NewReloader 创建证书加载器并立即加载证书，clientCaFile为空时不校验客户端证书
+func NewReloader(certFile, keyFile, clientCaFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, clientCaFile: clientCaFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload 重新加载证书
+func (r *Reloader) Reload() error {
+	modTime := r.latestModTime()
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate failed: %w", err)
+	}
+	if r.clientCaFile != "" {
+		pem, err := os.ReadFile(r.clientCaFile)
+		if err != nil {
+			return fmt.Errorf("read client ca failed: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("no valid certificate found in client ca file")
+		}
+		r.clientCas.Store(pool)
+	}
+	r.cert.Store(&cert)
+	r.modTime.Store(modTime)
+	return nil
+}
+
+// TLSConfig 生成每次握手都读取最新证书的tls配置
+func (r *Reloader) TLSConfig() *tls.Config {
+	getCertificate := func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return r.cert.Load(), nil
+	}
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: getCertificate,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			config := &tls.Config{
+				MinVersion:     tls.VersionTLS12,
+				GetCertificate: getCertificate,
+				NextProtos:     []string{"h2", "http/1.1"},
+			}
+			if pool := r.clientCas.Load(); pool != nil {
+				config.ClientCAs = pool
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return config, nil
+		},
+	}
+}
+
+// Watch 监听证书文件变更及SIGHUP信号，直到ctx取消
+func (r *Reloader) Watch(ctx context.Context) {
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGHUP)
+	defer signal.Stop(signalChan)
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signalChan:
+			r.reload(ctx, "SIGHUP")
+		case <-ticker.C:
+			if r.latestModTime() != r.modTime.Load() {
+				r.reload(ctx, "file changed")
+			}
+		}
+	}
+}
+
+func (r *Reloader) reload(ctx context.Context, reason string) {
+	if err := r.Reload(); err != nil {
+		log.Error(ctx, "Reload tls certificate failed, reason: %s, error: %v", reason, err)
+		return
+	}
+	log.Info(ctx, "Reload tls certificate success, reason: %s", reason)
+}
+
+// 证书相关文件的最新修改时间
+func (r *Reloader) latestModTime() int64 {
+	var latest int64
+	for _, file := range []string{r.certFile, r.keyFile, r.clientCaFile} {
+		if file == "" {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil && info.ModTime().UnixNano() > latest {
+			latest = info.ModTime().UnixNano()
+		}
+	}
+	return latest
+}
@@ -0,0 +1,176 @@
+//go:build linux
+
+package upgrade
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// 不使用FOUNDATION_前缀，避免与覆盖配置的环境变量混淆
+	envListenFds = "GRACEFUL_UPGRADE_LISTEN_FDS" // 继承的监听地址列表，按顺序对应从3开始的文件描述符
+	envReadyFd   = "GRACEFUL_UPGRADE_READY_FD"   // 子进程就绪后写入的管道文件描述符
+
+	// 等待子进程就绪的超时时间
+	readyTimeout = time.Minute
+)
+
+type namedListener struct {
+	addr     string
+	listener *net.TCPListener
+}
+
+var (
+	mu        sync.Mutex
+	inherited map[string]*os.File
+	listeners []namedListener
+)
+
+// Signals 触发平滑升级的信号
+func Signals() []os.Signal {
+	return []os.Signal{syscall.SIGUSR2}
+}
+
+// Listen 监听tcp地址，由父进程平滑升级启动时直接复用父进程的监听socket
+func Listen(addr string) (net.Listener, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	loadInherited()
+	var listener net.Listener
+	var err error
+	if file, ok := inherited[addr]; ok {
+		delete(inherited, addr)
+		listener, err = net.FileListener(file)
+		_ = file.Close()
+	} else {
+		listener, err = net.Listen("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tcpListener, ok := listener.(*net.TCPListener)
+	if !ok {
+		_ = listener.Close()
+		return nil, fmt.Errorf("inherited listener %s is not tcp", addr)
+	}
+	listeners = append(listeners, namedListener{addr: addr, listener: tcpListener})
+	return tcpListener, nil
+}
+
+// 解析父进程传递的监听socket
+func loadInherited() {
+	if inherited != nil {
+		return
+	}
+	inherited = make(map[string]*os.File)
+	value := os.Getenv(envListenFds)
+	if value == "" {
+		return
+	}
+	for i, addr := range strings.Split(value, ",") {
+		inherited[addr] = os.NewFile(uintptr(3+i), addr)
+	}
+	_ = os.Unsetenv(envListenFds)
+}
+
+// Ready 通知父进程当前进程已就绪，非平滑升级启动时不做任何处理
+func Ready() error {
+	value := os.Getenv(envReadyFd)
+	if value == "" {
+		return nil
+	}
+	_ = os.Unsetenv(envReadyFd)
+
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %s", envReadyFd, value)
+	}
+	pipe := os.NewFile(uintptr(fd), "ready")
+	defer pipe.Close()
+	_, err = pipe.Write([]byte{1})
+	return err
+}
+
+// Upgrade 启动新的进程并传递所有监听socket，新进程就绪后返回
+func Upgrade() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	var addrs []string
+	var files []*os.File
+	defer func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}()
+	for _, l := range listeners {
+		file, err := l.listener.File()
+		if err != nil {
+			return fmt.Errorf("get listener %s file failed: %w", l.addr, err)
+		}
+		addrs = append(addrs, l.addr)
+		files = append(files, file)
+	}
+
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer readyReader.Close()
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, readyWriter)
+	cmd.Env = append(os.Environ(),
+		envListenFds+"="+strings.Join(addrs, ","),
+		envReadyFd+"="+strconv.Itoa(3+len(files)),
+	)
+	err = cmd.Start()
+	_ = readyWriter.Close()
+	if err != nil {
+		return fmt.Errorf("start new process failed: %w", err)
+	}
+
+	// 子进程写入就绪标记，或者退出导致管道关闭
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		if n, err := readyReader.Read(buf); err != nil || n == 0 {
+			ready <- errors.New("new process exited before ready")
+			return
+		}
+		ready <- nil
+	}()
+
+	select {
+	case err = <-ready:
+	case <-time.After(readyTimeout):
+		err = errors.New("wait new process ready timeout")
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return err
+	}
+	// 新进程独立运行，父进程退出后由init进程接管
+	_ = cmd.Process.Release()
+	return nil
+}
@@ -0,0 +1,93 @@
+//go:build linux
+
+package upgrade
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/easonchen147/foundation/cfg"
+)
+
+// 平滑升级测试中由父进程重新执行的测试程序作为子进程运行
+func TestMain(m *testing.M) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") == "1" {
+		helperProcess()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// 子进程：复用父进程的监听socket，就绪后应答一个连接
+func helperProcess() {
+	listener, err := Listen(os.Getenv("UPGRADE_TEST_ADDR"))
+	if err != nil {
+		os.Exit(2)
+	}
+	if err = os.WriteFile(os.Getenv("UPGRADE_TEST_READY_FILE"), nil, 0600); err != nil {
+		os.Exit(3)
+	}
+	if err = Ready(); err != nil {
+		os.Exit(4)
+	}
+	_ = listener.(*net.TCPListener).SetDeadline(time.Now().Add(10 * time.Second))
+	conn, err := listener.Accept()
+	if err != nil {
+		os.Exit(5)
+	}
+	_, _ = conn.Write([]byte("child\n"))
+	_ = conn.Close()
+	os.Exit(0)
+}
+
+func TestUpgradeHandsOverListener(t *testing.T) {
+	const addr = "127.0.0.1:0"
+	listener, err := Listen(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+		mu.Lock()
+		listeners = nil
+		mu.Unlock()
+	})
+	readyFile := filepath.Join(t.TempDir(), "ready")
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	t.Setenv("UPGRADE_TEST_ADDR", addr)
+	t.Setenv("UPGRADE_TEST_READY_FILE", readyFile)
+
+	if err = Upgrade(); err != nil {
+		t.Fatal(err)
+	}
+	// Upgrade在子进程就绪后才返回，父进程此时才能开始排空
+	if _, err = os.Stat(readyFile); err != nil {
+		t.Fatalf("Upgrade() returned before the child was ready: %v", err)
+	}
+
+	// 父进程不再accept，连接只能由继承了socket的子进程处理
+	conn, err := net.DialTimeout("tcp", listener.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || reply != "child\n" {
+		t.Errorf("reply = %q, %v, want child", reply, err)
+	}
+}
+
+// 传给子进程的环境变量不能使用覆盖配置的前缀
+func TestEnvOutsideConfigPrefix(t *testing.T) {
+	for _, name := range []string{envListenFds, envReadyFd} {
+		if strings.HasPrefix(name, cfg.DefaultEnvPrefix+"_") {
+			t.Errorf("%s uses the config env prefix %s", name, cfg.DefaultEnvPrefix)
+		}
+	}
+}
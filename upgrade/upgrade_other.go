@@ -0,0 +1,29 @@
+//go:build !linux
+
+package upgrade
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+// Signals 非linux系统不支持平滑升级
+func Signals() []os.Signal {
+	return nil
+}
+
+// Listen 监听tcp地址
+func Listen(addr string) (net.Listener, error) {
+	return net.Listen("tcp", addr)
+}
+
+// Ready 非linux系统不支持平滑升级，不做任何处理
+func Ready() error {
+	return nil
+}
+
+// Upgrade 非linux系统不支持平滑升级
+func Upgrade() error {
+	return errors.New("graceful upgrade is only supported on linux")
+}
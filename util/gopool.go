@@ -24,7 +24,7 @@ func InitGoPool(max int) {
 type goPoolLogger struct{}
 
 func (g *goPoolLogger) Printf(format string, args ...interface{}) {
-	log.Debug(context.Background(), format, args)
+	log.Debug(context.Background(), format, args...)
 }
 
 var poolLogger = &goPoolLogger{}
@@ -32,3 +32,11 @@ var poolLogger = &goPoolLogger{}
 func GoInPool(task func()) error {
 	return goPool.Submit(task)
 }
+
+// GoPoolStats 获取协程池状态，未初始化时均为0
+func GoPoolStats() (running, free, capacity int) {
+	if goPool == nil {
+		return 0, 0, 0
+	}
+	return goPool.Running(), goPool.Free(), goPool.Cap()
+}
@@ -9,12 +9,13 @@ import (
 	"github.com/go-resty/resty/v2"
 )
 
-var httpClient *resty.Client
+var httpClient = resty.New().SetTimeout(5 * time.Second)
 
-func init() {
+// InitHttpClient 根据配置初始化http client
+func InitHttpClient(cfg *cfg.AppConfig) {
 	httpClient = resty.New()
-	httpClient.SetTimeout(time.Second * time.Duration(cfg.AppConf.HttpTimeout))
-	httpClient.SetDebug(cfg.AppConf.IsDevEnv())
+	httpClient.SetTimeout(time.Second * time.Duration(cfg.HttpTimeout))
+	httpClient.SetDebug(cfg.IsDevEnv())
 }
 
 // GetHttpClient 获取http client 实例
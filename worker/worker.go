@@ -0,0 +1,242 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/easonchen147/foundation/log"
+)
+
+// 后台任务状态
+const (
+	StatePending    = "pending"    // 未启动
+	StateRunning    = "running"    // 运行中
+	StateRestarting = "restarting" // 出错后等待重启
+	StateStopped    = "stopped"    // 正常退出
+	StateFailed     = "failed"     // 出错退出且不再重启
+)
+
+// Func 后台任务，ctx在应用停止时取消，任务应在ctx取消后尽快返回
+type Func func(ctx context.Context) error
+
+// Option 后台任务选项
+type Option func(*worker)
+
+// WithRestart 任务出错后按指数退避重启，退避时间从minBackoff开始翻倍，最大为maxBackoff
+func WithRestart(minBackoff, maxBackoff time.Duration) Option {
+	return func(w *worker) {
+		w.restart = true
+		w.minBackoff = minBackoff
+		w.maxBackoff = maxBackoff
+	}
+}
+
+// Status 后台任务状态
+type Status struct {
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	Restarts  int    `json:"restarts"`
+	LastError string `json:"lastError,omitempty"`
+}
+
+type worker struct {
+	name string
+	fn   Func
+
+	restart    bool
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu     sync.RWMutex
+	status Status
+}
+
+func (w *worker) setState(state string, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status.State = state
+	if err != nil {
+		w.status.LastError = err.Error()
+	}
+}
+
+func (w *worker) getStatus() Status {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.status
+}
+
+// run 执行任务，出错时根据配置重启，直到ctx取消
+func (w *worker) run(ctx context.Context) {
+	backoff := w.minBackoff
+	for {
+		w.setState(StateRunning, nil)
+		err := w.call(ctx)
+		if ctx.Err() != nil || err == nil {
+			w.setState(StateStopped, nil)
+			return
+		}
+		if !w.restart {
+			log.Error(ctx, "Worker %s failed, error: %v", w.name, err)
+			w.setState(StateFailed, err)
+			return
+		}
+
+		log.Error(ctx, "Worker %s failed, restart after %s, error: %v", w.name, backoff, err)
+		w.setState(StateRestarting, err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			w.setState(StateStopped, nil)
+			return
+		case <-timer.C:
+		}
+		w.mu.Lock()
+		w.status.Restarts++
+		w.mu.Unlock()
+
+		if backoff *= 2; backoff > w.maxBackoff {
+			backoff = w.maxBackoff
+		}
+	}
+}
+
+func (w *worker) call(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("worker panic: %v", r)
+		}
+	}()
+	return w.fn(ctx)
+}
+
+// Manager 后台任务管理器
+type Manager struct {
+	mu      sync.Mutex
+	workers []*worker
+	started bool
+	stopped bool // 停止后不再接受注册
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewManager 创建后台任务管理器
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// Register 注册后台任务，管理器启动后注册的任务会立即启动，管理器停止后返回错误
+func (m *Manager) Register(name string, fn Func, opts ...Option) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stopped {
+		return fmt.Errorf("worker %s not registered, workers are stopped", name)
+	}
+
+	for _, w := range m.workers {
+		if w.name == name {
+			return fmt.Errorf("worker %s already registered", name)
+		}
+	}
+	w := &worker{
+		name:       name,
+		fn:         fn,
+		minBackoff: time.Second,
+		maxBackoff: time.Minute,
+		status:     Status{Name: name, State: StatePending},
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	m.workers = append(m.workers, w)
+	if m.started {
+		m.launch(w)
+	}
+	return nil
+}
+
+// Start 启动所有后台任务
+func (m *Manager) Start(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ctx, m.cancel = context.WithCancel(ctx)
+	m.started = true
+	for _, w := range m.workers {
+		m.launch(w)
+	}
+}
+
+func (m *Manager) launch(w *worker) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		w.run(m.ctx)
+	}()
+}
+
+// Stop 取消所有后台任务并等待退出，ctx超时后不再等待
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	m.stopped = true
+	if !m.started {
+		m.mu.Unlock()
+		return nil
+	}
+	m.cancel()
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		var running []string
+		for _, status := range m.Statuses() {
+			if status.State == StateRunning || status.State == StateRestarting {
+				running = append(running, status.Name)
+			}
+		}
+		return fmt.Errorf("workers %v not stopped: %w", running, ctx.Err())
+	}
+}
+
+// Statuses 获取所有后台任务的状态
+func (m *Manager) Statuses() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]Status, 0, len(m.workers))
+	for _, w := range m.workers {
+		statuses = append(statuses, w.getStatus())
+	}
+	return statuses
+}
+
+// Check 检查指定任务的状态，出错退出或等待重启时返回错误
+func (m *Manager) Check(name string) error {
+	for _, status := range m.Statuses() {
+		if status.Name != name {
+			continue
+		}
+		switch status.State {
+		case StateFailed:
+			return errors.New("failed: " + status.LastError)
+		case StateRestarting:
+			return errors.New("restarting after error: " + status.LastError)
+		}
+		return nil
+	}
+	return fmt.Errorf("worker %s not found", name)
+}
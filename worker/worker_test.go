@@ -0,0 +1,151 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 等待cond成立，超时后测试失败
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in 2s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func status(m *Manager, name string) Status {
+	for _, s := range m.Statuses() {
+		if s.Name == name {
+			return s
+		}
+	}
+	return Status{}
+}
+
+func TestRestartWithBackoff(t *testing.T) {
+	m := NewManager()
+	var calls atomic.Int32
+	started := make(chan time.Time, 3)
+	err := m.Register("flaky", func(ctx context.Context) error {
+		started <- time.Now()
+		if calls.Add(1) < 3 {
+			return errors.New("unavailable")
+		}
+		<-ctx.Done()
+		return nil
+	}, WithRestart(20*time.Millisecond, 30*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Start(context.Background())
+	t.Cleanup(func() { _ = m.Stop(context.Background()) })
+
+	startedAt := [3]time.Time{<-started, <-started, <-started}
+	waitFor(t, func() bool { return status(m, "flaky").State == StateRunning })
+	if got := status(m, "flaky").Restarts; got != 2 {
+		t.Errorf("Restarts = %d, want 2", got)
+	}
+	// 第一次等待20ms，第二次翻倍后受限于最大退避30ms
+	if gap := startedAt[1].Sub(startedAt[0]); gap < 20*time.Millisecond {
+		t.Errorf("first backoff = %v, want >= 20ms", gap)
+	}
+	if gap := startedAt[2].Sub(startedAt[1]); gap < 30*time.Millisecond {
+		t.Errorf("second backoff = %v, want >= 30ms", gap)
+	}
+}
+
+func TestPanicMarksWorkerFailed(t *testing.T) {
+	m := NewManager()
+	if err := m.Register("panic", func(ctx context.Context) error { panic("boom") }); err != nil {
+		t.Fatal(err)
+	}
+	m.Start(context.Background())
+	t.Cleanup(func() { _ = m.Stop(context.Background()) })
+
+	waitFor(t, func() bool { return status(m, "panic").State == StateFailed })
+	if err := m.Check("panic"); err == nil || !strings.Contains(err.Error(), "worker panic: boom") {
+		t.Errorf("Check() = %v, want worker panic error", err)
+	}
+}
+
+func TestCheckFollowsState(t *testing.T) {
+	m := NewManager()
+	fail := make(chan struct{})
+	err := m.Register("consumer", func(ctx context.Context) error {
+		select {
+		case <-fail:
+			return errors.New("broker down")
+		case <-ctx.Done():
+			return nil
+		}
+	}, WithRestart(time.Hour, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Check("missing"); err == nil {
+		t.Error("Check(missing) = nil")
+	}
+	m.Start(context.Background())
+	waitFor(t, func() bool { return status(m, "consumer").State == StateRunning })
+	if err := m.Check("consumer"); err != nil {
+		t.Errorf("Check() while running = %v", err)
+	}
+
+	close(fail)
+	waitFor(t, func() bool { return status(m, "consumer").State == StateRestarting })
+	if err := m.Check("consumer"); err == nil || !strings.Contains(err.Error(), "broker down") {
+		t.Errorf("Check() while restarting = %v, want broker down", err)
+	}
+
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Check("consumer"); err != nil {
+		t.Errorf("Check() after stop = %v", err)
+	}
+}
+
+func TestStopTimeoutNamesStuckWorkers(t *testing.T) {
+	m := NewManager()
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	if err := m.Register("stuck", func(ctx context.Context) error { <-release; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Register("polite", func(ctx context.Context) error { <-ctx.Done(); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	m.Start(context.Background())
+	waitFor(t, func() bool { return status(m, "stuck").State == StateRunning })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := m.Stop(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "[stuck]") {
+		t.Errorf("Stop() = %v, want timeout naming only stuck", err)
+	}
+}
+
+func TestRegisterAfterStop(t *testing.T) {
+	m := NewManager()
+	m.Start(context.Background())
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var ran atomic.Bool
+	if err := m.Register("late", func(ctx context.Context) error { ran.Store(true); return nil }); err == nil {
+		t.Error("Register() after Stop = nil, want error")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if ran.Load() {
+		t.Error("worker registered after Stop was started")
+	}
+}